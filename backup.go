@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/urfave/cli/v2"
+)
+
+func backupShamirAction(c *cli.Context) error {
+	if err := nkcli.CheckShamirParams(c.Int("threshold"), c.Int("shares")); err != nil {
+		return usageError{err}
+	}
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	kind, language, name, secret, err := backupSecret(c, db)
+
+	if err != nil {
+		return err
+	}
+
+	shares, err := nkcli.ShamirSplit(kind, language, secret, c.Int("threshold"), c.Int("shares"))
+
+	if err != nil {
+		return err
+	}
+
+	records := make([]*nkcli.ShareRecord, len(shares))
+
+	for i, s := range shares {
+		records[i] = &nkcli.ShareRecord{Index: s.Index, Threshold: s.Threshold, Shares: len(shares), Words: s.Words()}
+	}
+
+	return render(records, func() {
+		fmt.Printf("\nShamir backup of %v, any %v of %v shares recover it.\nWrite each share down and keep them in different places.\n\n", name, c.Int("threshold"), len(shares))
+
+		for _, r := range records {
+			fmt.Printf("  Share %v of %v:\n\n", r.Index, r.Shares)
+
+			words := strings.Fields(r.Words)
+
+			for i := 0; i < len(words); i += 8 {
+				end := i + 8
+
+				if end > len(words) {
+					end = len(words)
+				}
+
+				fmt.Printf("    %v\n", strings.Join(words[i:end], " "))
+			}
+
+			fmt.Println()
+		}
+	})
+}
+
+// backupSecret unlocks the key, or the entropy of the stored mnemonic with
+// --seed, to split.
+func backupSecret(c *cli.Context, db *nkcli.DB) (kind byte, language string, name string, secret []byte, err error) {
+	if fp := c.String("seed"); c.IsSet("seed") {
+		seed, err := chooseSeed(db, fp)
+
+		if err != nil {
+			return 0, "", "", nil, err
+		}
+
+		mnemonic, err := unlockSeed(seed)
+
+		if err != nil {
+			return 0, "", "", nil, err
+		}
+
+		entropy, language, err := nkcli.MnemonicEntropy(mnemonic)
+
+		return nkcli.ShareKindEntropy, language, "seed " + seed.Fingerprint, entropy, err
+	}
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return
+	}
+
+	info, err := unlockKey(db, key.Pubkey)
+
+	if err != nil {
+		return
+	}
+
+	secret, err = hex.DecodeString(info.Privkey)
+	name, _ = nip19.EncodePublicKey(key.Pubkey)
+
+	return nkcli.ShareKindKey, "", name, secret, err
+}
+
+func importShamir(c *cli.Context, db *nkcli.DB) ([]string, error) {
+	shares, err := readShares()
+
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := nkcli.ShamirCombine(shares)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if shares[0].Kind == nkcli.ShareKindKey {
+		return importRawKeys(db, []string{hex.EncodeToString(secret)})
+	}
+
+	mnemonic, err := nkcli.MnemonicFromEntropy(secret, shares[0].Language)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return importMnemonic(c, db, strings.Fields(mnemonic))
+}
+
+// readShares asks for shares until the threshold is reached, or reads one
+// share a line from stdin when it isn't a terminal.
+func readShares() ([]*nkcli.ShamirShare, error) {
+	shares := make([]*nkcli.ShamirShare, 0)
+	interactive := nkcli.IsTerminal()
+	scanner := bufio.NewScanner(os.Stdin)
+
+	for len(shares) == 0 || len(shares) < shares[0].Threshold {
+		var line string
+
+		if interactive {
+			answer, err := prompter.Ask(fmt.Sprintf("Enter share #%v: ", len(shares)+1))
+
+			if err != nil {
+				return nil, err
+			}
+
+			line = answer
+		} else if scanner.Scan() {
+			line = scanner.Text()
+		} else {
+			break
+		}
+
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		share, err := nkcli.ParseShamirShare(line)
+
+		if err != nil && interactive {
+			fmt.Printf("  %v\n", err)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(shares) == 0 {
+			fmt.Printf("  This backup needs %v shares.\n", share.Threshold)
+		}
+
+		shares = append(shares, share)
+	}
+
+	return shares, nil
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errImportSources     = errors.New("Use only one of --from, --shamir and key arguments")
+	errInvalidImportFile = errors.New("Invalid import file")
+	errInvalidNsec       = errors.New("Invalid nsec")
+	errInvalidKey        = errors.New("Invalid private key")
+	errAlreadyStored     = errors.New("already stored")
+	errRepeatedInFile    = errors.New("repeated in the file")
+)
+
+// batchKey is a key found in an import file, or a pubkey to watch.
+type batchKey struct {
+	source     string
+	priv       string
+	pub        string
+	derivation *nkcli.Derivation
+	watch      bool
+	mnemonic   *batchMnemonic
+}
+
+// batchMnemonic is a mnemonic found in an import file, kept to store it.
+type batchMnemonic struct {
+	words string
+	seed  []byte
+	saved []string
+}
+
+type batchIssue struct {
+	source string
+	err    error
+}
+
+// importFromFile imports every key of the --from file with one passphrase
+// and returns what was imported, skipped and already known.
+func importFromFile(c *cli.Context, db *nkcli.DB) (added []string, skipped []*batchIssue, duplicates []*batchIssue, err error) {
+	if c.Bool("shamir") || c.Args().Len() > 0 {
+		return nil, nil, nil, usageError{errImportSources}
+	}
+
+	accounts, err := nkcli.ParseAccounts(c.String("account"))
+
+	if err != nil {
+		return nil, nil, nil, usageError{err}
+	}
+
+	buf, err := readInput(c.String("from"))
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	entries, err := nkcli.ParseImportFile(buf)
+
+	if err != nil {
+		return nil, nil, nil, errors.Join(errInvalidImportFile, err)
+	}
+
+	keys, mnemonics, skipped, err := readBatchKeys(c, entries, accounts)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fresh := make([]*batchKey, 0, len(keys))
+	duplicates = make([]*batchIssue, 0)
+	seen := make(map[string]bool)
+
+	// Private keys go first, a pubkey to watch is a duplicate of its key.
+	for _, watch := range []bool{false, true} {
+		for _, k := range keys {
+			if k.watch != watch {
+				continue
+			}
+
+			switch {
+			case seen[k.pub]:
+				duplicates = append(duplicates, &batchIssue{k.source, errRepeatedInFile})
+			case db.Has(k.pub), k.watch && db.IsWatch(k.pub):
+				duplicates = append(duplicates, &batchIssue{k.source, errAlreadyStored})
+
+				// Keys imported before families were tracked join theirs.
+				if k.derivation != nil {
+					db.SaveDerivation(k.pub, k.derivation)
+				}
+			default:
+				fresh = append(fresh, k)
+			}
+
+			seen[k.pub] = true
+		}
+	}
+
+	added = make([]string, 0, len(fresh))
+
+	if len(fresh) > 0 {
+		fmt.Printf("\nFound %v new keys:\n\n", len(fresh))
+
+		for _, k := range fresh {
+			npub, _ := nip19.EncodePublicKey(k.pub)
+			mark := ""
+
+			if k.watch {
+				mark = " (watch-only)"
+			}
+
+			fmt.Printf("  %v: %v%v\n", k.source, npub, mark)
+		}
+
+		fmt.Println()
+
+		if ok, err := prompter.Confirm(fmt.Sprintf("Import %v keys? [y/n]", len(fresh))); err != nil || !ok {
+			return nil, skipped, duplicates, err
+		}
+
+		if added, err = saveBatchKeys(c, db, fresh, mnemonics, accounts[0]); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return added, skipped, duplicates, nil
+}
+
+// renderImportSummary fetches metadata of the added keys and renders what
+// was imported, skipped and already known.
+func renderImportSummary(c *cli.Context, db *nkcli.DB, added []string, skipped []*batchIssue, duplicates []*batchIssue) error {
+	updateMetadata(c, db, added)
+
+	imported, err := keyRecords(db, added)
+
+	if err != nil {
+		return err
+	}
+
+	record := &nkcli.ImportRecord{
+		Imported:   imported,
+		Skipped:    batchIssueRecords(skipped),
+		Duplicates: batchIssueRecords(duplicates),
+	}
+
+	return render(record, func() {
+		fmt.Printf("\nImported %v, skipped %v, duplicate %v.\n", len(added), len(skipped), len(duplicates))
+		printBatchIssues("Skipped", skipped)
+		printBatchIssues("Duplicates", duplicates)
+	})
+}
+
+func batchIssueRecords(issues []*batchIssue) []*nkcli.ImportIssueRecord {
+	records := make([]*nkcli.ImportIssueRecord, 0, len(issues))
+
+	for _, it := range issues {
+		records = append(records, &nkcli.ImportIssueRecord{Source: it.source, Error: it.err.Error()})
+	}
+
+	return records
+}
+
+// readBatchKeys decrypts and derives the private keys of entries, asking
+// the ncryptsec password and mnemonic passphrase once if needed.
+func readBatchKeys(c *cli.Context, entries []*nkcli.ImportEntry, accounts []uint32) (keys []*batchKey, mnemonics []*batchMnemonic, skipped []*batchIssue, err error) {
+	var ncryptsecPass, mnemonicPass *string
+
+	for _, e := range entries {
+		if e.Error != nil {
+			skipped = append(skipped, &batchIssue{e.Source, e.Error})
+			continue
+		}
+
+		var priv string
+
+		switch e.Kind {
+		case nkcli.EntryNpub:
+			if list := nkcli.SerializeKeys([]string{e.Value}); len(list) > 0 {
+				keys = append(keys, &batchKey{source: e.Source, pub: list[0], watch: true})
+			} else {
+				skipped = append(skipped, &batchIssue{e.Source, errInvalidWatchKey})
+			}
+
+			continue
+		case nkcli.EntryNsec:
+			list := nkcli.SerializeKeys([]string{e.Value})
+
+			if len(list) == 0 {
+				skipped = append(skipped, &batchIssue{e.Source, errInvalidNsec})
+				continue
+			}
+
+			priv = list[0]
+		case nkcli.EntryHex:
+			priv = strings.ToLower(e.Value)
+		case nkcli.EntryNcryptsec:
+			if ncryptsecPass == nil {
+				pass, err := ncryptsecPassword()
+
+				if err != nil {
+					return nil, nil, nil, err
+				}
+
+				ncryptsecPass = &pass
+			}
+
+			if priv, err = nkcli.DecryptNcryptsec(e.Value, *ncryptsecPass); err != nil {
+				skipped = append(skipped, &batchIssue{e.Source, err})
+				continue
+			}
+		case nkcli.EntryMnemonic:
+			if mnemonicPass == nil {
+				pass, err := mnemonicPassphrase(c, false)
+
+				if err != nil {
+					return nil, nil, nil, err
+				}
+
+				mnemonicPass = &pass
+			}
+
+			m := &batchMnemonic{words: e.Value, seed: nkcli.SeedFromWords(e.Value, *mnemonicPass)}
+			mnemonics = append(mnemonics, m)
+
+			for _, account := range accounts {
+				k := &batchKey{source: fmt.Sprintf("%v account %v", e.Source, account), mnemonic: m}
+
+				if k.priv, k.derivation, err = nkcli.DeriveKey(m.seed, account); err != nil {
+					return nil, nil, nil, err
+				}
+
+				if k.pub, err = nostr.GetPublicKey(k.priv); err != nil {
+					return nil, nil, nil, err
+				}
+
+				keys = append(keys, k)
+			}
+
+			continue
+		}
+
+		pub, err := nostr.GetPublicKey(priv)
+
+		if err != nil {
+			skipped = append(skipped, &batchIssue{e.Source, errInvalidKey})
+			continue
+		}
+
+		keys = append(keys, &batchKey{source: e.Source, priv: priv, pub: pub})
+	}
+
+	return keys, mnemonics, skipped, nil
+}
+
+// saveBatchKeys saves keys encrypted with one new passphrase, asked unless
+// all are watched, and the mnemonics too with --store-mnemonic.
+func saveBatchKeys(c *cli.Context, db *nkcli.DB, keys []*batchKey, mnemonics []*batchMnemonic, account uint32) ([]string, error) {
+	var pass []byte
+	var err error
+
+	// Watched pubkeys are sorted last.
+	if !keys[0].watch {
+		if pass, err = prompter.ReadPassphrase(keys[0].pub, nkcli.PurposeNew, "\nEnter a password to protect your keys: "); err != nil {
+			return nil, err
+		}
+	}
+
+	added := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		if k.watch {
+			if err = db.SaveWatch(k.pub); err != nil {
+				return nil, err
+			}
+
+			added = append(added, k.pub)
+			continue
+		}
+
+		privBuf, err := hex.DecodeString(k.priv)
+
+		if err != nil {
+			return nil, err
+		}
+
+		enced, err := nkcli.Encrypt(privBuf, pass)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err = db.SaveKey(k.pub, enced); err != nil {
+			return nil, err
+		}
+
+		if k.derivation != nil {
+			if err = db.SaveDerivation(k.pub, k.derivation); err != nil {
+				return nil, err
+			}
+		}
+
+		prompter.Approve(k.pub, nkcli.PurposeNew, pass)
+
+		if k.mnemonic != nil {
+			k.mnemonic.saved = append(k.mnemonic.saved, k.pub)
+		}
+
+		added = append(added, k.pub)
+	}
+
+	if c.Bool("store-mnemonic") {
+		for _, m := range mnemonics {
+			if err = storeMnemonic(db, m.words, m.seed, account, m.saved, pass); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return added, nil
+}
+
+// ncryptsecPassword reads the password of ncryptsec keys from
+// $NKCLI_NCRYPTSEC_PASSWORD or asks it.
+func ncryptsecPassword() (string, error) {
+	if v, ok := os.LookupEnv("NKCLI_NCRYPTSEC_PASSWORD"); ok {
+		return v, nil
+	}
+
+	pass, err := prompter.ReadSecret("Enter the password of your ncryptsec keys:")
+
+	return string(pass), err
+}
+
+func printBatchIssues(title string, issues []*batchIssue) {
+	if len(issues) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%v:\n", title)
+
+	for _, it := range issues {
+		fmt.Printf("  %v: %v\n", it.source, it.err)
+	}
+}
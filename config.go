@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+func configPathAction(c *cli.Context) error {
+	return render(map[string]string{"path": c.String("config")}, func() {
+		fmt.Println(c.String("config"))
+	})
+}
+
+func configShowAction(c *cli.Context) error {
+	keys := config.Keys()
+	values := make(map[string]string, len(keys))
+
+	for _, k := range keys {
+		values[k], _ = config.Get(k)
+	}
+
+	rec := map[string]any{
+		"path":   c.String("config"),
+		"config": values,
+		"effective": map[string]any{
+			"db":     c.String("db"),
+			"relays": bootRelays(c),
+			"output": outputFormat,
+		},
+	}
+
+	return render(rec, func() {
+		fmt.Printf("# %v\n\n", c.String("config"))
+
+		for _, k := range keys {
+			v := values[k]
+
+			if len(v) == 0 {
+				v = "(unset)"
+			}
+
+			fmt.Printf("%v = %v\n", k, v)
+		}
+
+		fmt.Printf("\n# effective\n\ndb = %v\nrelays = %v\noutput = %v\n", c.String("db"), bootRelays(c), outputFormat)
+	})
+}
+
+func configGetAction(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return usageError{errors.New("Usage: nkcli config get <key>")}
+	}
+
+	v, err := config.Get(c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	return render(map[string]string{"key": c.Args().First(), "value": v}, func() {
+		fmt.Println(v)
+	})
+}
+
+func configSetAction(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return usageError{errors.New("Usage: nkcli config set <key> <value>")}
+	}
+
+	key, value := c.Args().Get(0), c.Args().Get(1)
+
+	if key == "relays" || strings.HasPrefix(key, "key_relays.") {
+		for _, url := range strings.Split(value, ",") {
+			if url = strings.TrimSpace(url); len(url) == 0 {
+				continue
+			}
+
+			if err := checkRelayUrl(url); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := config.Set(key, value); err != nil {
+		return err
+	}
+
+	return config.Save(c.String("config"))
+}
+
+func configUnsetAction(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return usageError{errors.New("Usage: nkcli config unset <key>")}
+	}
+
+	if err := config.Set(c.Args().First(), ""); err != nil {
+		return err
+	}
+
+	return config.Save(c.String("config"))
+}
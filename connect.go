@@ -3,7 +3,6 @@ package main
 import (
 	"errors"
 	"fmt"
-	"strconv"
 
 	nkcli "github.com/mdzz-club/nkcli/internal"
 	"github.com/urfave/cli/v2"
@@ -11,8 +10,7 @@ import (
 
 func connectAction(c *cli.Context) error {
 	if c.Args().Len() == 0 {
-		fmt.Printf("You need pass a nostrconnect:// arg")
-		return nil
+		return usageError{errors.New("You need pass a nostrconnect:// arg")}
 	}
 
 	cu, err := nkcli.ParseURL(c.Args().Get(0))
@@ -29,51 +27,47 @@ func connectAction(c *cli.Context) error {
 		fmt.Printf("App description: %v\n", cu.Metadata.Description)
 	}
 
-	db, err := nkcli.Open(c.String("db"))
-
-	if err != nil {
-		return err
+	if err := nkcli.CheckPoW(c.Int("pow")); err != nil {
+		return usageError{err}
 	}
 
-	keys, err := db.List()
+	db, err := nkcli.Open(c.String("db"))
 
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("\nYou have %v keys:\n\n", len(keys))
-
-	nkcli.PrintKeyList(keys)
-
-	fmt.Print("  🔗 Choose your key: ")
+	fmt.Println()
 
-	line := nkcli.Scanline()
-
-	n, err := strconv.Atoi(line)
+	usedPub, err := chooseKey(db, c.String("key"))
 
 	if err != nil {
 		return err
 	}
 
-	if n < 1 || n > len(keys) {
-		return errors.New("Invalid key No.")
+	if usedPub.Watch {
+		return errWatchConnection
 	}
 
-	usedPub := keys[n-1]
-
 	allows := []string{}
 
 	if c.Bool("allow-all") {
 		fmt.Println("\nNOTICE: This connection will allow all requests by default.")
-		allows = []string{"get_public_key", "sign_event", "delegate", "get_relays", "nip04_encrypt", "nip04_decrypt"}
+		allows = append(allows, nkcli.GrantableMethods...)
+	}
+
+	if c.Bool("preview-decrypt") {
+		fmt.Println("\nNOTICE: Messages will be decrypted and previewed before asking permission.")
 	}
 
 	conn := &nkcli.Connection{
-		AppID:  cu.Pubkey,
-		Relay:  cu.Relay,
-		PubKey: usedPub.Pubkey,
-		Acked:  false,
-		Allows: allows,
+		AppID:          cu.Pubkey,
+		Relay:          cu.Relay,
+		PubKey:         usedPub.Pubkey,
+		Acked:          false,
+		Allows:         allows,
+		PreviewDecrypt: c.Bool("preview-decrypt"),
+		PoW:            c.Int("pow"),
 		Metadata: &nkcli.ConnMetadata{
 			Name:        cu.Metadata.Name,
 			Description: cu.Metadata.Description,
@@ -81,8 +75,11 @@ func connectAction(c *cli.Context) error {
 		},
 	}
 
-	db.SetConnection(conn)
-	fmt.Print("\nConnection info saved!\nRun nkcli without subcommand to serve it.\n")
+	if err = db.SetConnection(conn); err != nil {
+		return err
+	}
 
-	return nil
+	return render(conn.Record(), func() {
+		fmt.Print("\nConnection info saved!\nRun nkcli without subcommand to serve it.\n")
+	})
 }
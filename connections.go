@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errInvalidConnNo     = errors.New("Invalid connection No.")
+	errUnknownConnection = errors.New("No connection matches")
+	errAmbiguousConn     = errors.New("More than one connection matches, use No. or App ID")
+	errUnknownMethod     = errors.New("Unknown method")
+	errNoConnections     = errors.New("You don't have any connections.")
+	errWatchConnection   = errors.New("Watch-only keys can't sign for a connection, choose another key")
+)
+
+func connectionsListAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	conns, err := db.ListConnection()
+
+	if err != nil {
+		return err
+	}
+
+	keys, err := db.List()
+
+	if err != nil {
+		return err
+	}
+
+	return render(nkcli.ConnectionRecords(conns), func() {
+		if len(conns) == 0 {
+			fmt.Println("You don't have any connections.")
+			return
+		}
+
+		fmt.Printf("You have %v connections:\n\n", len(conns))
+
+		nkcli.PrintConnectionList(conns, keys)
+	})
+}
+
+func connectionsShowAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	conn, err := chooseConnection(db, c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	keys, err := db.List()
+
+	if err != nil {
+		return err
+	}
+
+	return render(conn.Record(), func() {
+		nkcli.PrintConnection(conn, keys)
+	})
+}
+
+func connectionsEditAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	conn, err := chooseConnection(db, c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	for _, m := range append(c.StringSlice("allow"), c.StringSlice("deny")...) {
+		if !contains(nkcli.GrantableMethods, m) {
+			return errors.Join(errUnknownMethod, errors.New(m))
+		}
+	}
+
+	if c.IsSet("relay") {
+		if err = checkRelayUrl(c.String("relay")); err != nil {
+			return err
+		}
+	}
+
+	// Rebinding first, grants given along are for the new key.
+	if c.IsSet("key") {
+		key, err := chooseKey(db, c.String("key"))
+
+		if err != nil {
+			return err
+		}
+
+		if key.Watch {
+			return errWatchConnection
+		}
+
+		if key.Pubkey != conn.PubKey {
+			conn.PubKey = key.Pubkey
+			conn.Acked = false
+			fmt.Println("Connection rebound, it will be acked again with the new key on next serve.")
+
+			// Grants were approved for the old key.
+			if !c.Bool("keep-grants") {
+				conn.Allows = []string{}
+				conn.DecryptPeers = nil
+				fmt.Println("Permissions of the old key were cleared, use --keep-grants to keep them.")
+			}
+		}
+	}
+
+	if c.Bool("allow-all") {
+		conn.Allows = append([]string{}, nkcli.GrantableMethods...)
+	}
+
+	if c.Bool("deny-all") {
+		conn.Allows = []string{}
+		conn.DecryptPeers = nil
+	}
+
+	for _, m := range c.StringSlice("allow") {
+		if !contains(conn.Allows, m) {
+			conn.Allows = append(conn.Allows, m)
+		}
+	}
+
+	conn.Allows = without(conn.Allows, c.StringSlice("deny"))
+
+	for _, p := range nkcli.SerializeKeys(c.StringSlice("allow-peer")) {
+		if !contains(conn.DecryptPeers, p) {
+			conn.DecryptPeers = append(conn.DecryptPeers, p)
+		}
+	}
+
+	conn.DecryptPeers = without(conn.DecryptPeers, nkcli.SerializeKeys(c.StringSlice("deny-peer")))
+
+	if c.IsSet("preview-decrypt") {
+		conn.PreviewDecrypt = c.Bool("preview-decrypt")
+	}
+
+	if c.IsSet("pow") {
+		if err := nkcli.CheckPoW(c.Int("pow")); err != nil {
+			return usageError{err}
+		}
+
+		conn.PoW = c.Int("pow")
+	}
+
+	if c.IsSet("relay") {
+		conn.Relay = nkcli.NormalizeRelayURL(c.String("relay"))
+	}
+
+	if err = db.SetConnection(conn); err != nil {
+		return err
+	}
+
+	keys, err := db.List()
+
+	if err != nil {
+		return err
+	}
+
+	return render(conn.Record(), func() {
+		fmt.Print("Connection saved:\n\n")
+
+		nkcli.PrintConnection(conn, keys)
+	})
+}
+
+func connectionsRenameAction(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return usageError{errors.New("Usage: nkcli connections rename <No.|App ID|name> <new name>")}
+	}
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	conn, err := chooseConnection(db, c.Args().Get(0))
+
+	if err != nil {
+		return err
+	}
+
+	old := conn.Metadata.Name
+	conn.Metadata.Name = c.Args().Get(1)
+
+	if err = db.SetConnection(conn); err != nil {
+		return err
+	}
+
+	return render(conn.Record(), func() {
+		fmt.Printf("Connection '%v' renamed to '%v'.\n", old, conn.Metadata.Name)
+	})
+}
+
+func chooseConnection(db *nkcli.DB, arg string) (*nkcli.Connection, error) {
+	conns, err := db.ListConnection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conns) == 0 {
+		return nil, errNoConnections
+	}
+
+	if len(arg) == 0 {
+		fmt.Printf("You have %v connections:\n\n", len(conns))
+
+		for i, c := range conns {
+			fmt.Printf("%v. %v\n", i+1, c.Metadata.Name)
+		}
+
+		fmt.Println()
+
+		n, err := prompter.Choose("  Choose one: ", len(conns))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if n < 0 {
+			return nil, errInvalidConnNo
+		}
+
+		return conns[n], nil
+	}
+
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n < 1 || n > len(conns) {
+			return nil, errInvalidConnNo
+		}
+
+		return conns[n-1], nil
+	}
+
+	matches := make([]*nkcli.Connection, 0)
+
+	for _, c := range conns {
+		if c.AppID == arg || strings.EqualFold(c.Metadata.Name, arg) {
+			matches = append(matches, c)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.Join(errUnknownConnection, errors.New(arg))
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, errAmbiguousConn
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, i := range list {
+		if i == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func without(list []string, remove []string) []string {
+	result := make([]string, 0, len(list))
+
+	for _, i := range list {
+		if !contains(remove, i) {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errInvalidDelegatee = errors.New("Invalid delegatee pubkey")
+)
+
+func delegateCreateAction(c *cli.Context) error {
+	delegatee := nkcli.SerializeKeys([]string{c.String("to")})
+
+	if len(delegatee) == 0 {
+		return errInvalidDelegatee
+	}
+
+	conds, err := delegateConditions(c, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	if err = conds.Check(c.Duration("max-lifetime"), time.Now()); err != nil {
+		return err
+	}
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context, 10*time.Second)
+	router := newRouter(c, db)
+
+	if e := router.FetchLatest(ctx, nostr.Filter{Kinds: []int{0}, Authors: delegatee, Limit: 1}); e != nil {
+		db.SaveMetadataEvent(e)
+	}
+
+	cancel()
+
+	fmt.Printf("\nDelegate to %v with these conditions:\n\n%v\n\n", nkcli.DescribePubkey(db, delegatee[0]), conds)
+
+	info, err := unlockKey(db, key.Pubkey)
+
+	if err != nil {
+		return err
+	}
+
+	d, err := nkcli.CreateDelegation(info, delegatee[0], conds, "nkcli")
+
+	if err != nil {
+		return err
+	}
+
+	if err = db.SaveDelegation(d); err != nil {
+		return err
+	}
+
+	return render(d, func() {
+		fmt.Printf("\nDelegation tag:\n\n[\"delegation\",\"%v\",\"%v\",\"%v\"]\n", d.Delegator, d.Conditions, d.Sig)
+	})
+}
+
+func delegateConditions(c *cli.Context, now time.Time) (*nkcli.DelegationConds, error) {
+	clauses := make([]string, 0)
+
+	if len(c.String("conditions")) > 0 {
+		clauses = append(clauses, c.String("conditions"))
+	}
+
+	for _, k := range c.IntSlice("kind") {
+		clauses = append(clauses, fmt.Sprintf("kind=%d", k))
+	}
+
+	since, err := parseTimeArg(c.String("since"), now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if since != nil {
+		clauses = append(clauses, fmt.Sprintf("created_at>%d", since.Unix()))
+	}
+
+	until, err := parseTimeArg(c.String("until"), now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if until != nil {
+		clauses = append(clauses, fmt.Sprintf("created_at<%d", until.Unix()))
+	}
+
+	return nkcli.ParseConditions(strings.Join(clauses, "&"))
+}
+
+func delegateListAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	list, err := db.ListDelegations()
+
+	if err != nil {
+		return err
+	}
+
+	return render(list, func() {
+		if len(list) == 0 {
+			fmt.Println("You haven't issued any delegations.")
+			return
+		}
+
+		fmt.Printf("You have issued %v delegations:\n\n", len(list))
+
+		for i, d := range list {
+			from, _ := nip19.EncodePublicKey(d.Delegator)
+			to, _ := nip19.EncodePublicKey(d.Delegatee)
+			created := time.Unix(d.CreatedAt, 0)
+
+			fmt.Printf("  %v. %v -> %v\n     Conditions: %v\n     Issued: %v via %v\n\n", i+1, from, to, d.Conditions, formatTime(&created), d.Issuer)
+		}
+	})
+}
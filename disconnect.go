@@ -7,14 +7,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 
 	nkcli "github.com/mdzz-club/nkcli/internal"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip04"
 	"github.com/urfave/cli/v2"
-	"golang.org/x/crypto/ssh/terminal"
+)
+
+var (
+	errRelayUnreachable = errors.New("Relay is unreachable")
+	errPublishRejected  = errors.New("Relay rejected the event")
+	errConnectionsKept  = errors.New("connections were kept, use --force or --offline to remove them anyway")
+	// errDisconnectQueued isn't a failure, the app is notified on next serve.
+	errDisconnectQueued = errors.New("Disconnect queued for next serve")
 )
 
 func disconnectAction(c *cli.Context) error {
@@ -25,80 +32,179 @@ func disconnectAction(c *cli.Context) error {
 		return err
 	}
 
-	conns, err := db.ListConnection()
+	defer db.Close()
 
-	if err != nil {
+	conns, err := selectDisconnects(c, db)
+
+	if err != nil || len(conns) == 0 {
 		return err
 	}
 
-	if len(conns) == 0 {
-		fmt.Println("You don't have any connections.")
-		return nil
-	}
+	keys := make(map[string]*nkcli.KeyInfo)
+	router := newRouter(c, db)
+	records := make([]*nkcli.DisconnectRecord, 0, len(conns))
+	kept := 0
+
+	for _, conn := range conns {
+		rec := &nkcli.DisconnectRecord{AppID: conn.AppID, Name: conn.Metadata.Name}
+		records = append(records, rec)
+
+		fmt.Printf("\n✂️  %v\n", conn.Metadata.Name)
+
+		if !c.Bool("offline") {
+			err = notifyDisconnect(c.Context, db, router, conn, keys)
+
+			if errors.Is(err, errDisconnectQueued) {
+				rec.Queued = true
+				fmt.Printf("   %v is unreachable, disconnect queued and will be retried on next serve.\n", conn.Relay)
+			} else if err != nil {
+				rec.Error = err.Error()
+				fmt.Printf("   Notify app failed: %v\n", err)
+
+				if !c.Bool("force") {
+					kept++
+					continue
+				}
+			} else {
+				rec.Notified = true
+			}
+		}
 
-	fmt.Printf("You have %v connections:\n\n", len(conns))
+		if err = db.Disconnect(conn.AppID); err != nil {
+			return err
+		}
 
-	for i, c := range conns {
-		fmt.Printf("%v. %v\n", i+1, c.Metadata.Name)
+		rec.Removed = true
+		fmt.Println("   Connection removed.")
 	}
 
-	fmt.Print("\n  Choose one to disconnect ✂️ : ")
-
-	line := nkcli.Scanline()
+	if err = render(records, func() {
+		if kept == 0 {
+			fmt.Println("\nYour connection has been disconnected.")
+		}
+	}); err != nil {
+		return err
+	}
 
-	if len(line) == 0 {
-		return nil
+	if kept > 0 {
+		return fmt.Errorf("%v %w", kept, errConnectionsKept)
 	}
 
-	n, err := strconv.Atoi(line)
+	return nil
+}
+
+func selectDisconnects(c *cli.Context, db *nkcli.DB) ([]*nkcli.Connection, error) {
+	conns, err := db.ListConnection()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if n < 1 || n > len(conns) {
-		return errors.New("Invalid No. Out of range.")
+	if len(conns) == 0 {
+		fmt.Println("You don't have any connections.")
+		return nil, nil
 	}
 
-	conn := conns[n-1]
+	names, ids, pubs := c.StringSlice("app"), c.StringSlice("id"), nkcli.SerializeKeys(c.StringSlice("key"))
 
-	if conn.KeyInfo == nil {
-		fmt.Print("Enter your passphrase to unlock your private key:")
-		pass, err := terminal.ReadPassword(0)
+	if len(names) == 0 && len(ids) == 0 && len(pubs) == 0 {
+		fmt.Printf("You have %v connections:\n\n", len(conns))
 
-		if err != nil {
-			return err
+		for i, c := range conns {
+			fmt.Printf("%v. %v\n", i+1, c.Metadata.Name)
 		}
 
-		info, err := db.GetKey(conn.PubKey, pass)
+		fmt.Println()
+
+		n, err := prompter.Choose("  Choose one to disconnect ✂️ : ", len(conns))
 
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		if n < 0 {
+			return nil, errInvalidConnNo
 		}
 
-		conn.KeyInfo = info
+		return conns[n : n+1], nil
 	}
 
-	err = sendDisconnect(c.Context, conn)
+	matched := make([]*nkcli.Connection, 0)
 
-	if err != nil {
-		return err
+	for _, conn := range conns {
+		byName := false
+
+		for _, n := range names {
+			byName = byName || strings.EqualFold(conn.Metadata.Name, n)
+		}
+
+		if byName || contains(ids, conn.AppID) || contains(pubs, conn.PubKey) {
+			matched = append(matched, conn)
+		}
 	}
 
-	db.Disconnect(conn.AppID)
+	if len(matched) == 0 {
+		fmt.Println("No connections matched.")
+		return nil, nil
+	}
 
-	fmt.Println("\nYour connection has been disconnected.")
+	fmt.Printf("These %v connections will be disconnected:\n\n", len(matched))
 
-	return nil
+	for i, conn := range matched {
+		fmt.Printf("  %v. %v (%v)\n", i+1, conn.Metadata.Name, conn.AppID)
+	}
+
+	if ok, err := prompter.Confirm("\n[y/n]"); err != nil || !ok {
+		return nil, err
+	}
+
+	return matched, nil
 }
 
-func sendDisconnect(ctx context.Context, conn *nkcli.Connection) error {
-	relay, err := nostr.RelayConnect(ctx, conn.Relay)
+// notifyDisconnect tells the app of conn it's disconnected. When its relay
+// is unreachable the event is queued and errDisconnectQueued returned.
+func notifyDisconnect(ctx context.Context, db *nkcli.DB, router *nkcli.Router, conn *nkcli.Connection, keys map[string]*nkcli.KeyInfo) error {
+	info, ok := keys[conn.PubKey]
+
+	if !ok {
+		var err error
+
+		if info, err = unlockKey(db, conn.PubKey); err != nil {
+			return err
+		}
+
+		keys[conn.PubKey] = info
+	}
+
+	conn.KeyInfo = info
+
+	event, err := disconnectEvent(conn)
 
 	if err != nil {
 		return err
 	}
 
+	err = sendDisconnect(ctx, router, conn.Relay, event)
+
+	if errors.Is(err, errRelayUnreachable) {
+		q := &nkcli.QueuedEvent{
+			Relay:    conn.Relay,
+			Event:    event,
+			QueuedAt: time.Now().Unix(),
+			Reason:   "disconnect " + conn.Metadata.Name,
+		}
+
+		if err = db.QueueEvent(q); err != nil {
+			return err
+		}
+
+		return errDisconnectQueued
+	}
+
+	return err
+}
+
+func disconnectEvent(conn *nkcli.Connection) (*nostr.Event, error) {
 	rb := make([]byte, 16)
 	rand.Read(rb)
 	randomId := hex.EncodeToString(rb)
@@ -107,17 +213,21 @@ func sendDisconnect(ctx context.Context, conn *nkcli.Connection) error {
 	shared, err := nip04.ComputeSharedSecret(conn.AppID, conn.KeyInfo.Privkey)
 
 	if err != nil {
-		fmt.Printf("%v\n", err)
-		return err
+		return nil, err
 	}
 
 	jstr, err := json.Marshal(data)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	content, err := nip04.Encrypt(string(jstr), shared)
+
+	if err != nil {
+		return nil, err
+	}
+
 	event := &nostr.Event{
 		PubKey:    conn.PubKey,
 		Kind:      24133,
@@ -125,9 +235,53 @@ func sendDisconnect(ctx context.Context, conn *nkcli.Connection) error {
 		Tags:      nostr.Tags{{"p", conn.AppID}},
 		Content:   content,
 	}
-	err = event.Sign(conn.KeyInfo.Privkey)
 
-	relay.Publish(ctx, *event)
+	if err = event.Sign(conn.KeyInfo.Privkey); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func sendDisconnect(ctx context.Context, router *nkcli.Router, url string, event *nostr.Event) error {
+	res := router.PublishTo(ctx, []string{url}, event)[0]
+
+	if res.Err != nil {
+		return errors.Join(errRelayUnreachable, res.Err)
+	}
+
+	if res.Status == nostr.PublishStatusFailed {
+		return errors.Join(errPublishRejected, errors.New(url))
+	}
 
 	return nil
 }
+
+func flushQueue(c *cli.Context, db *nkcli.DB) {
+	list, err := db.ListQueued()
+
+	if err != nil || len(list) == 0 {
+		return
+	}
+
+	fmt.Printf("Retrying %v queued events...\n", len(list))
+
+	router := newRouter(c, db)
+
+	for _, q := range list {
+		err := sendDisconnect(c.Context, router, q.Relay, q.Event)
+
+		if errors.Is(err, errRelayUnreachable) {
+			fmt.Printf("  %v: still unreachable, keep queued.\n", q.Reason)
+			continue
+		}
+
+		if err != nil {
+			fmt.Printf("  %v: %v, dropped.\n", q.Reason, err)
+		} else {
+			fmt.Printf("  %v: sent.\n", q.Reason)
+		}
+
+		db.Dequeue(q.Event.ID)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errInvalidPeer = errors.New("Invalid recipient, use npub1 or hex pubkey")
+	errEmptyDM     = errors.New("Message is empty")
+)
+
+func dmSendAction(c *cli.Context) error {
+	peer, err := parsePeer(c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	text := strings.Join(c.Args().Tail(), " ")
+
+	if len(text) == 0 {
+		buf, err := io.ReadAll(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		text = strings.TrimRight(string(buf), "\n")
+	}
+
+	if len(text) == 0 {
+		return errEmptyDM
+	}
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nSend to:\n\n%v\n", nkcli.DescribeMessage(db, peer, text))
+
+	info, err := unlockKey(db, key.Pubkey)
+
+	if err != nil {
+		return err
+	}
+
+	router := newRouter(c, db)
+	records := make([]*nkcli.PublishedRecord, 0, 2)
+	ok := 0
+
+	if c.Bool("nip04") {
+		ev, err := nkcli.NewNip04Message(info, peer, text)
+
+		if err != nil {
+			return err
+		}
+
+		archiveEvent(c, db, ev, "nkcli dm")
+
+		fmt.Printf("Sending NIP-04 message %v...\n\n", ev.ID)
+
+		results := router.Publish(c.Context, ev, peer)
+		ok = acceptedCount(results)
+		records = append(records, &nkcli.PublishedRecord{Event: ev, Results: nkcli.PublishRecords(results)})
+
+		printPublishResults(results)
+	} else {
+		_, wraps, err := nkcli.NewGiftWraps(info, peer, text)
+
+		if err != nil {
+			return err
+		}
+
+		recipients := []string{peer}
+
+		if peer != info.Pubkey {
+			recipients = append(recipients, info.Pubkey)
+		}
+
+		for _, p := range recipients {
+			fmt.Printf("Sending gift wrap for %v...\n\n", nkcli.DescribePubkey(db, p))
+
+			// Only gift wraps are archived, the seal would reveal the sender.
+			archiveEvent(c, db, wraps[p], "nkcli dm")
+
+			results := router.PublishTo(c.Context, router.DMRelays(c.Context, p), wraps[p])
+			records = append(records, &nkcli.PublishedRecord{Event: wraps[p], Results: nkcli.PublishRecords(results)})
+
+			if p == peer {
+				ok = acceptedCount(results)
+			}
+
+			printPublishResults(results)
+			fmt.Println()
+		}
+	}
+
+	if err = render(records, func() {}); err != nil {
+		return err
+	}
+
+	if ok == 0 {
+		return errNoRelayAccepted
+	}
+
+	return nil
+}
+
+func dmReadAction(c *cli.Context) error {
+	peer := ""
+
+	if c.Args().Len() > 0 {
+		p, err := parsePeer(c.Args().First())
+
+		if err != nil {
+			return err
+		}
+
+		peer = p
+	}
+
+	var since *time.Time
+
+	if c.IsSet("since") {
+		t, err := parseTimeArg(c.String("since"), time.Now())
+
+		if err != nil {
+			return err
+		}
+
+		since = t
+	}
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	info, err := unlockKey(db, key.Pubkey)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Fetching messages...")
+
+	msgs, failed := newRouter(c, db).FetchMessages(c.Context, info, peer, since, c.Int("limit"))
+
+	if failed > 0 {
+		fmt.Printf("%v events couldn't be decrypted and were skipped.\n", failed)
+	}
+
+	return render(nkcli.MessageRecords(msgs), func() {
+		fmt.Println()
+
+		if len(msgs) == 0 {
+			fmt.Println("No messages.")
+		} else if len(peer) > 0 {
+			printConversation(db, info.Pubkey, peer, msgs)
+		} else {
+			printConversations(db, info.Pubkey, msgs)
+		}
+	})
+}
+
+func printConversation(db *nkcli.DB, me string, peer string, msgs []*nkcli.Message) {
+	fmt.Printf("── %v ──\n\n", nkcli.DescribePubkey(db, peer))
+
+	for _, m := range msgs {
+		who, arrow := "you", "→"
+
+		if m.From != me {
+			who, arrow = "them", "←"
+		}
+
+		mark := ""
+
+		if m.Protocol == nkcli.ProtocolNip04 {
+			mark = " [nip04]"
+		}
+
+		fmt.Printf("  %v %v %v%v:\n", m.CreatedAt.Format(time.DateTime), arrow, who, mark)
+		fmt.Printf("%v\n\n", indentLines(m.Text, "      "))
+	}
+}
+
+func printConversations(db *nkcli.DB, me string, msgs []*nkcli.Message) {
+	threads := make(map[string][]*nkcli.Message)
+
+	for _, m := range msgs {
+		p := m.Peer(me)
+		threads[p] = append(threads[p], m)
+	}
+
+	peers := make([]string, 0, len(threads))
+
+	for p := range threads {
+		peers = append(peers, p)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		a, b := threads[peers[i]], threads[peers[j]]
+		return a[len(a)-1].CreatedAt.After(b[len(b)-1].CreatedAt)
+	})
+
+	fmt.Printf("You have %v conversations:\n\n", len(peers))
+
+	for i, p := range peers {
+		list := threads[p]
+		last := list[len(list)-1]
+		text := strings.ReplaceAll(last.Text, "\n", " ")
+
+		if r := []rune(text); len(r) > 60 {
+			text = string(r[:60]) + "…"
+		}
+
+		fmt.Printf("  %v. %v\n", i+1, nkcli.DescribePubkey(db, p))
+		fmt.Printf("     %v messages, last %v\n", len(list), last.CreatedAt.Format(time.DateTime))
+		fmt.Printf("     %v\n\n", text)
+	}
+
+	fmt.Println("Run `nkcli dm read <npub>` to open a conversation.")
+}
+
+func parsePeer(s string) (string, error) {
+	if strings.HasPrefix(s, "nsec1") {
+		return "", errInvalidPeer
+	}
+
+	if list := nkcli.SerializeKeys([]string{s}); len(list) > 0 {
+		return list[0], nil
+	}
+
+	return "", errors.Join(errInvalidPeer, errors.New(s))
+}
+
+func indentLines(s string, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
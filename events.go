@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errEventIdRequired = errors.New("You need pass an event ID (hex or note1)")
+)
+
+func eventsListAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	q := &nkcli.ArchiveQuery{
+		Kinds:  c.IntSlice("kind"),
+		Search: c.String("search"),
+		Limit:  c.Int("limit"),
+	}
+
+	if c.IsSet("key") {
+		list := nkcli.SerializeKeys([]string{c.String("key")})
+
+		if len(list) == 0 {
+			return errors.Join(errUnknownKey, errors.New(c.String("key")))
+		}
+
+		q.Pubkey = list[0]
+	}
+
+	list, err := db.QueryArchive(q)
+
+	if err != nil {
+		return err
+	}
+
+	return render(list, func() {
+		if len(list) == 0 {
+			fmt.Println("No archived events found.")
+
+			if !archiveEnabled(c) {
+				fmt.Println("Archive is disabled, enable it with 'nkcli config set archive true'.")
+			}
+
+			return
+		}
+
+		for _, a := range list {
+			note, _ := nip19.EncodeNote(a.Event.ID)
+			content := strings.ReplaceAll(a.Event.Content, "\n", " ")
+
+			if r := []rune(content); len(r) > 60 {
+				content = string(r[:60]) + "..."
+			}
+
+			fmt.Printf("  %v\n     Kind: %v  Created: %v  Via: %v\n", note, a.Event.Kind, formatTime(&a.Event.CreatedAt), a.Source)
+
+			if len(content) > 0 {
+				fmt.Printf("     %v\n", content)
+			}
+
+			fmt.Println()
+		}
+	})
+}
+
+func eventsShowAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	a, err := findArchived(db, c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	return render(a, func() {
+		fmt.Printf("%v\n", nkcli.DescribeEvent(nil, a.Event))
+
+		str, _ := json.MarshalIndent(a.Event, "", "  ")
+		fmt.Printf("%s\n", str)
+	})
+}
+
+func eventsRebroadcastAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	a, err := findArchived(db, c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	recipients := make([]string, 0)
+
+	for _, t := range a.Event.Tags.GetAll([]string{"p", ""}) {
+		recipients = append(recipients, t.Value())
+	}
+
+	router := newRouter(c, db)
+
+	fmt.Print("Publishing to relays...\n\n")
+
+	// A gift wrap goes where its recipient reads messages, as dm sends it.
+	if a.Event.Kind == nkcli.KindGiftWrap && len(recipients) > 0 {
+		return renderPublish(a.Event, router.PublishTo(c.Context, router.DMRelays(c.Context, recipients[0]), a.Event))
+	}
+
+	return renderPublish(a.Event, router.Publish(c.Context, a.Event, recipients...))
+}
+
+func eventsExportAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	list, err := db.QueryArchive(&nkcli.ArchiveQuery{Kinds: c.IntSlice("kind")})
+
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = stdout
+
+	if p := c.String("file"); len(p) > 0 && p != "-" {
+		f, err := os.Create(p)
+
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+
+	for i := len(list) - 1; i >= 0; i-- {
+		if err = enc.Encode(list[i].Event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findArchived(db *nkcli.DB, id string) (*nkcli.ArchivedEvent, error) {
+	if len(id) == 0 {
+		return nil, errEventIdRequired
+	}
+
+	if strings.HasPrefix(id, "note1") {
+		_, v, err := nip19.Decode(id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		id = v.(string)
+	}
+
+	return db.FindArchived(id)
+}
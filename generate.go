@@ -2,36 +2,36 @@ package main
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
 
 	nkcli "github.com/mdzz-club/nkcli/internal"
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip06"
 	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/tyler-smith/go-bip39"
 	"github.com/urfave/cli/v2"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
-func generateAction(c *cli.Context) error {
-	fmt.Print("Here's your mnemonic words:\n\n")
-
-	ent, err := bip39.NewEntropy(128)
+var (
+	errMnemonicCheck = errors.New("Mnemonic words don't match, the key is not saved")
+	errSkipCheck     = errors.New("Use --no-check to skip checking the mnemonic")
+)
 
-	if err != nil {
-		return err
+func generateAction(c *cli.Context) error {
+	var (
+		priv, words string
+		derivation  *nkcli.Derivation
+		err         error
+	)
+
+	if c.IsSet("vanity-prefix") || c.IsSet("hex-prefix") {
+		priv, err = vanityKey(c)
+	} else {
+		priv, words, derivation, err = mnemonicKey(c)
 	}
 
-	words, err := bip39.NewMnemonic(ent)
-
-	if err != nil {
-		return err
-	}
-
-	fmt.Printf("%v\n\n", words)
-
-	priv, err := nip06.PrivateKeyFromSeed(nip06.SeedFromWords(words))
-
 	if err != nil {
 		return err
 	}
@@ -42,9 +42,7 @@ func generateAction(c *cli.Context) error {
 		return err
 	}
 
-	fmt.Print("Enter a passphrase to protect your key:")
-
-	password, err := terminal.ReadPassword(0)
+	password, err := prompter.ReadPassphrase(pub, nkcli.PurposeNew, "Enter a passphrase to protect your key:")
 
 	if err != nil {
 		return err
@@ -73,13 +71,98 @@ func generateAction(c *cli.Context) error {
 		return err
 	}
 
+	if derivation != nil {
+		if err = db.SaveDerivation(pub, derivation); err != nil {
+			return err
+		}
+
+		if c.Bool("store-mnemonic") {
+			if err = db.SaveSeed(derivation.Fingerprint, pub, words, password); err != nil {
+				return err
+			}
+		}
+	}
+
+	prompter.Approve(pub, nkcli.PurposeNew, password)
+
 	bech32Pub, err := nip19.EncodePublicKey(pub)
 
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("\n\nYour public key:\n%v\n%v\n", pub, bech32Pub)
+	return render((&nkcli.KeyInfo{Pubkey: pub, Derivation: derivation}).Record(), func() {
+		if derivation != nil {
+			fmt.Printf("\n\nYour public key (%v):\n%v\n%v\n", derivation.Path(), pub, bech32Pub)
+		} else {
+			fmt.Printf("\n\nYour public key:\n%v\n%v\n", pub, bech32Pub)
+		}
+	})
+}
+
+// mnemonicKey makes a new mnemonic and derives the key of --account from it.
+func mnemonicKey(c *cli.Context) (string, string, *nkcli.Derivation, error) {
+	words, err := nkcli.NewMnemonic(c.Int("words"), c.String("language"))
+
+	if err != nil {
+		return "", "", nil, usageError{err}
+	}
+
+	fmt.Print("Here's your mnemonic words:\n\n")
+	fmt.Printf("%v\n\n", words)
+
+	if !c.Bool("no-check") {
+		if err = checkMnemonic(words); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	mnemonicPass, err := mnemonicPassphrase(c, true)
+
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	priv, derivation, err := nkcli.DeriveKey(nkcli.SeedFromWords(words, mnemonicPass), uint32(c.Uint("account")))
+
+	if err != nil {
+		return "", "", nil, usageError{err}
+	}
+
+	return priv, words, derivation, nil
+}
+
+// checkMnemonic asks for three random words of mnemonic so it's known to be
+// written down before the key is saved.
+func checkMnemonic(mnemonic string) error {
+	words := strings.Fields(mnemonic)
+	picks := rand.Perm(len(words))[:3]
+
+	sort.Ints(picks)
+
+	fmt.Print("Write down your mnemonic words, then enter these to check:\n\n")
+
+	for _, i := range picks {
+		for attempt := 1; ; attempt++ {
+			answer, err := prompter.Ask(fmt.Sprintf("  Word #%v: ", i+1))
+
+			if err != nil {
+				return errors.Join(err, errSkipCheck)
+			}
+
+			if nkcli.NormalizeMnemonic(strings.ToLower(answer)) == words[i] {
+				break
+			}
+
+			if attempt == 3 {
+				return errMnemonicCheck
+			}
+
+			fmt.Println("  Wrong word, try again.")
+		}
+	}
+
+	fmt.Println()
 
 	return nil
 }
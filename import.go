@@ -10,15 +10,17 @@ import (
 
 	nkcli "github.com/mdzz-club/nkcli/internal"
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip06"
 	"github.com/nbd-wtf/go-nostr/nip19"
 	"github.com/urfave/cli/v2"
-	"golang.org/x/crypto/ssh/terminal"
+)
+
+var (
+	errInvalidMnemonic = errors.New("Invalid mnemonic words")
+	errInvalidWatchKey = errors.New("Invalid pubkey to watch, use npub1 or hex")
 )
 
 func importAction(c *cli.Context) error {
 	isRaw := c.Bool("raw")
-	relays := c.StringSlice("relay")
 
 	db, err := nkcli.Open(c.String("db"))
 
@@ -26,28 +28,73 @@ func importAction(c *cli.Context) error {
 		return err
 	}
 
-	if relays == nil {
-		relays = DefaultRelays
-	}
-
 	keys := make([]string, 0)
-	if isRaw {
-		if keys, err = importRawKeys(db, c.Args().Slice()); err != nil {
+	if c.IsSet("from") {
+		added, skipped, duplicates, err := importFromFile(c, db)
+
+		if err != nil {
 			return err
 		}
+
+		return renderImportSummary(c, db, added, skipped, duplicates)
+	} else if c.Bool("shamir") {
+		if keys, err = importShamir(c, db); err != nil {
+			return err
+		}
+	} else if c.Bool("watch") {
+		if keys, err = importWatchKeys(db, c.Args().Slice()); err != nil {
+			return err
+		}
+	} else if isRaw {
+		secrets, watches := make([]string, 0), make([]string, 0)
+
+		// An npub has no private key, it can only be watched.
+		for _, arg := range c.Args().Slice() {
+			if strings.HasPrefix(arg, "npub1") {
+				watches = append(watches, arg)
+			} else {
+				secrets = append(secrets, arg)
+			}
+		}
+
+		if keys, err = importRawKeys(db, secrets); err != nil {
+			return err
+		}
+
+		watched, err := importWatchKeys(db, watches)
+
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, watched...)
 	} else {
-		if keys, err = importMnemonic(db, c.Args().Slice()); err != nil {
+		if keys, err = importMnemonic(c, db, c.Args().Slice()); err != nil {
 			return err
 		}
 	}
 
+	return updateNewKeys(c, db, keys)
+}
+
+// updateNewKeys fetches metadata and relay lists of just added keys and
+// renders them.
+func updateNewKeys(c *cli.Context, db *nkcli.DB, keys []string) error {
+	updateMetadata(c, db, keys)
+
+	return renderKeys(db, keys, func() {})
+}
+
+// updateMetadata fetches metadata and relay lists of keys.
+func updateMetadata(c *cli.Context, db *nkcli.DB, keys []string) {
 	if len(keys) == 0 {
-		return nil
+		return
 	}
 
 	fmt.Print("\n\nNow update metadatas...\n\n")
 
 	wg := new(sync.WaitGroup)
+	router := newRouter(c, db)
 	ctx := context.WithValue(c.Context, "db", db)
 	for _, k := range keys {
 		info, err := db.GetKey(k, nil)
@@ -57,12 +104,39 @@ func importAction(c *cli.Context) error {
 		}
 
 		wg.Add(1)
-		go nkcli.UpdateRelays(ctx, info, relays, wg)
+		go nkcli.UpdateRelays(ctx, info, router, wg)
 	}
 
 	wg.Wait()
+}
 
-	return nil
+// renderKeys renders the stored keys among pubs, table is called for the human format.
+func renderKeys(db *nkcli.DB, pubs []string, table func()) error {
+	records, err := keyRecords(db, pubs)
+
+	if err != nil {
+		return err
+	}
+
+	return render(records, table)
+}
+
+func keyRecords(db *nkcli.DB, pubs []string) ([]*nkcli.KeyRecord, error) {
+	list, err := db.List()
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*nkcli.KeyRecord, 0, len(pubs))
+
+	for _, k := range list {
+		if contains(pubs, k.Pubkey) {
+			records = append(records, k.Record())
+		}
+	}
+
+	return records, nil
 }
 
 func importRawKeys(db *nkcli.DB, keys []string) (added []string, err error) {
@@ -72,40 +146,41 @@ func importRawKeys(db *nkcli.DB, keys []string) (added []string, err error) {
 			continue
 		}
 
-		fmt.Print("Enter a passphrase to protect your key:")
-		pass, err := terminal.ReadPassword(0)
+		pub, err := nostr.GetPublicKey(sec)
 
 		if err != nil {
 			return nil, err
 		}
 
-		seckey, err := hex.DecodeString(sec)
+		if db.Has(pub) {
+			fmt.Printf("\n%v is exists, skip.\n", pub)
+			continue
+		}
+
+		pass, err := prompter.ReadPassphrase(pub, nkcli.PurposeNew, "Enter a passphrase to protect your key:")
 
 		if err != nil {
 			return nil, err
 		}
 
-		encKey, err := nkcli.Encrypt(seckey, pass)
+		seckey, err := hex.DecodeString(sec)
 
 		if err != nil {
 			return nil, err
 		}
 
-		pub, err := nostr.GetPublicKey(sec)
+		encKey, err := nkcli.Encrypt(seckey, pass)
 
 		if err != nil {
 			return nil, err
 		}
 
-		if db.Has(pub) {
-			fmt.Printf("\n%v is exists, skip.\n", pub)
-			continue
-		}
-
 		if err = db.SaveKey(pub, encKey); err != nil {
 			return nil, err
 		}
 
+		prompter.Approve(pub, nkcli.PurposeNew, pass)
+
 		fmt.Printf("\n%v saved.", pub)
 
 		added = append(added, pub)
@@ -114,71 +189,191 @@ func importRawKeys(db *nkcli.DB, keys []string) (added []string, err error) {
 	return
 }
 
-func importMnemonic(db *nkcli.DB, words []string) (keys []string, err error) {
-	ws := strings.Join(words, " ")
+// importWatchKeys adds pubkeys as watch-only entries, they get metadata and
+// relays but can't sign.
+func importWatchKeys(db *nkcli.DB, pubs []string) (added []string, err error) {
+	for _, arg := range pubs {
+		list := nkcli.SerializeKeys([]string{arg})
 
-	if !nip06.ValidateWords(ws) {
-		err = errors.New("Invalid mnemonic words")
-		return
+		if strings.HasPrefix(arg, "nsec1") || len(list) == 0 {
+			return nil, errors.Join(errInvalidWatchKey, errors.New(arg))
+		}
+
+		if db.Has(list[0]) || db.IsWatch(list[0]) {
+			fmt.Printf("\n%v is exists, skip.\n", list[0])
+			continue
+		}
+
+		if err = db.SaveWatch(list[0]); err != nil {
+			return nil, err
+		}
+
+		fmt.Printf("\n%v is watched.", list[0])
+
+		added = append(added, list[0])
 	}
 
-	seed := nip06.SeedFromWords(ws)
-	priv, err := nip06.PrivateKeyFromSeed(seed)
+	return
+}
 
-	if err != nil {
+func importMnemonic(c *cli.Context, db *nkcli.DB, words []string) (keys []string, err error) {
+	ws := strings.Join(words, " ")
+
+	if _, ok := nkcli.MnemonicLanguage(ws, c.String("language")); !ok {
+		err = errInvalidMnemonic
 		return
 	}
 
-	pub, err := nostr.GetPublicKey(priv)
+	accounts, err := nkcli.ParseAccounts(c.String("account"))
 
 	if err != nil {
-		return nil, err
+		return nil, usageError{err}
 	}
 
-	npub, err := nip19.EncodePublicKey(pub)
+	mnemonicPass, err := mnemonicPassphrase(c, false)
 
 	if err != nil {
-		return nil, err
+		return
 	}
 
-	fmt.Printf("\n\nThis is your public key: %v\n  Bech32 Encoded: %v\n\nIs corrent? [y/n]", pub, npub)
+	seed := nkcli.SeedFromWords(ws, mnemonicPass)
+	keys, pass, err := importAccounts(db, seed, accounts)
 
-	if nkcli.Scanline() != "y" {
+	if err != nil || !c.Bool("store-mnemonic") {
 		return
 	}
 
-	if db.Has(pub) {
-		fmt.Printf("\nThis key is already exists.\n")
-		return
+	return keys, storeMnemonic(db, ws, seed, accounts[0], keys, pass)
+}
+
+// importAccounts derives and saves accounts of seed, it returns the new keys
+// and the passphrase protecting them.
+func importAccounts(db *nkcli.DB, seed []byte, accounts []uint32) (keys []string, pass []byte, err error) {
+	derived := make([]*derivedKey, 0, len(accounts))
+
+	fmt.Print("\n\nThese are your public keys:\n\n")
+
+	for _, account := range accounts {
+		k := new(derivedKey)
+
+		if k.priv, k.derivation, err = nkcli.DeriveKey(seed, account); err != nil {
+			return nil, nil, err
+		}
+
+		if k.pub, err = nostr.GetPublicKey(k.priv); err != nil {
+			return nil, nil, err
+		}
+
+		npub, err := nip19.EncodePublicKey(k.pub)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fmt.Printf("  %v\n  %v\n  Bech32 Encoded: %v\n\n", k.derivation.Path(), k.pub, npub)
+
+		derived = append(derived, k)
 	}
 
-	if err != nil {
-		return
+	if ok, err := prompter.Confirm("Is corrent? [y/n]"); err != nil || !ok {
+		return nil, nil, err
+	}
+
+	for _, k := range derived {
+		if db.Has(k.pub) {
+			// Keys imported before families were tracked join theirs.
+			fmt.Printf("\n%v is exists, skip.\n", k.pub)
+			db.SaveDerivation(k.pub, k.derivation)
+			continue
+		}
+
+		if pass == nil {
+			if pass, err = prompter.ReadPassphrase(k.pub, nkcli.PurposeNew, "\nEnter a password to protect your keys: "); err != nil {
+				return
+			}
+		}
+
+		privBuf, err := hex.DecodeString(k.priv)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		enced, err := nkcli.Encrypt(privBuf, pass)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err = db.SaveKey(k.pub, enced); err != nil {
+			return nil, nil, err
+		}
+
+		if err = db.SaveDerivation(k.pub, k.derivation); err != nil {
+			return nil, nil, err
+		}
+
+		prompter.Approve(k.pub, nkcli.PurposeNew, pass)
+
+		keys = append(keys, k.pub)
 	}
 
-	fmt.Print("\nEnter a password to protect your key: ")
-	pass, err := terminal.ReadPassword(0)
+	fmt.Printf("\n%v keys have been saved.", len(keys))
+
+	return
+}
+
+// storeMnemonic saves mnemonic encrypted with pass under the first of saved,
+// the keys of seed just saved with pass. When there are none, it's recorded
+// against the key of account and encrypted with its passphrase.
+func storeMnemonic(db *nkcli.DB, mnemonic string, seed []byte, account uint32, saved []string, pass []byte) error {
+	priv, derivation, err := nkcli.DeriveKey(seed, account)
 
 	if err != nil {
-		return
+		return err
 	}
 
-	privBuf, err := hex.DecodeString(priv)
+	pub, err := nostr.GetPublicKey(priv)
 
 	if err != nil {
-		return
+		return err
 	}
 
-	enced, err := nkcli.Encrypt(privBuf, pass)
+	if len(saved) > 0 {
+		pub = saved[0]
+	} else if pass, err = readKeyPassphrase(db, pub, "\nEnter the passphrase of your key to protect your mnemonic: "); err != nil {
+		return err
+	}
+
+	if err = db.SaveSeed(derivation.Fingerprint, pub, mnemonic, pass); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nMnemonic of seed %v has been saved.", derivation.Fingerprint)
+
+	return nil
+}
+
+// readKeyPassphrase reads the passphrase of the stored key pub and checks it
+// decrypts the key.
+func readKeyPassphrase(db *nkcli.DB, pub string, prompt string) ([]byte, error) {
+	pass, err := prompter.ReadPassphrase(pub, nkcli.PurposeUnlock, prompt)
 
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	db.SaveKey(pub, enced)
+	if _, err = db.GetKey(pub, pass); err != nil {
+		return nil, err
+	}
 
-	fmt.Printf("\nYour key has been saved.")
-	keys = append(keys, pub)
+	prompter.Approve(pub, nkcli.PurposeUnlock, pass)
 
-	return
+	return pass, nil
+}
+
+type derivedKey struct {
+	priv       string
+	pub        string
+	derivation *nkcli.Derivation
 }
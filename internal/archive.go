@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+type ArchivedEvent struct {
+	Event    *nostr.Event `json:"event"`
+	SignedAt int64        `json:"signed_at"`
+	Source   string       `json:"source"`
+}
+
+type ArchiveQuery struct {
+	Pubkey string
+	Kinds  []int
+	Search string
+	Limit  int
+}
+
+var (
+	errEventNotFound = errors.New("Event not found in archive")
+)
+
+func archiveKey(e *nostr.Event) ([]byte, error) {
+	id, err := hex.DecodeString(e.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(e.CreatedAt.Unix()))
+
+	return append(key, id...), nil
+}
+
+func (d *DB) ArchiveEvent(e *nostr.Event, source string) error {
+	key, err := archiveKey(e)
+
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(&ArchivedEvent{
+		Event:    e,
+		SignedAt: time.Now().Unix(),
+		Source:   source,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return d.saveData(bucketArchive, key, buf)
+}
+
+func (d *DB) FindArchived(id string) (*ArchivedEvent, error) {
+	raw, err := hex.DecodeString(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ArchivedEvent
+
+	err = d.Db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketArchive).Cursor()
+
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if len(k) != 8+len(raw) || string(k[8:]) != string(raw) {
+				continue
+			}
+
+			result = new(ArchivedEvent)
+
+			return json.Unmarshal(v, result)
+		}
+
+		return errEventNotFound
+	})
+
+	return result, err
+}
+
+func (d *DB) QueryArchive(q *ArchiveQuery) (list []*ArchivedEvent, err error) {
+	terms := strings.Fields(strings.ToLower(q.Search))
+	list = make([]*ArchivedEvent, 0)
+
+	err = d.Db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketArchive).Cursor()
+
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			a := new(ArchivedEvent)
+
+			if err := json.Unmarshal(v, a); err != nil {
+				return err
+			}
+
+			if !q.match(a.Event, terms) {
+				continue
+			}
+
+			list = append(list, a)
+
+			if q.Limit > 0 && len(list) >= q.Limit {
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return
+}
+
+func (q *ArchiveQuery) match(e *nostr.Event, terms []string) bool {
+	if len(q.Pubkey) > 0 && e.PubKey != q.Pubkey {
+		return false
+	}
+
+	if len(q.Kinds) > 0 {
+		found := false
+
+		for _, k := range q.Kinds {
+			found = found || k == e.Kind
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(terms) == 0 {
+		return true
+	}
+
+	text := make([]string, 0, len(e.Tags)+2)
+	text = append(text, e.Content, strconv.Itoa(e.Kind))
+
+	for _, t := range e.Tags {
+		text = append(text, strings.Join(t, " "))
+	}
+
+	haystack := strings.ToLower(strings.Join(text, "\n"))
+
+	for _, t := range terms {
+		if !strings.Contains(haystack, t) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of secrets found in an import file.
+const (
+	EntryNsec      = "nsec"
+	EntryHex       = "hex"
+	EntryNcryptsec = "ncryptsec"
+	EntryMnemonic  = "mnemonic"
+	EntryNpub      = "npub"
+)
+
+// ImportEntry is a secret read from an import file, Source tells where it
+// was found: a line number or a JSON path.
+type ImportEntry struct {
+	Source string
+	Kind   string
+	Value  string
+	Error  error
+}
+
+var (
+	errUnrecognizedEntry = errors.New("not a nsec, hex key, ncryptsec, mnemonic or npub")
+	errNoImportEntries   = errors.New("No keys found in the import file")
+)
+
+// privateKeyFields are JSON field names signer exports use for private keys,
+// compared in lower case without _ and -.
+var privateKeyFields = []string{"privkey", "privatekey", "seckey", "secretkey", "secret", "sk", "nsec", "key"}
+
+// ParseImportFile reads the secrets of buf, either a JSON export of a signer
+// or a list with one nsec, hex key, ncryptsec, mnemonic or npub to watch a
+// line. Empty lines and lines starting with # are ignored.
+func ParseImportFile(buf []byte) ([]*ImportEntry, error) {
+	buf = bytes.TrimSpace(buf)
+	entries := make([]*ImportEntry, 0)
+
+	if len(buf) > 0 && (buf[0] == '{' || buf[0] == '[') {
+		var v any
+
+		if err := json.Unmarshal(buf, &v); err != nil {
+			return nil, err
+		}
+
+		entries = jsonEntries(entries, "$", "", v)
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(buf))
+
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+
+			if len(line) == 0 || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			e := &ImportEntry{Source: fmt.Sprintf("line %v", n), Value: line}
+
+			e.Kind = entryKind(line, true)
+
+			if strings.HasPrefix(line, "npub1") {
+				e.Kind = EntryNpub
+			}
+
+			switch {
+			case len(e.Kind) > 0:
+			case len(strings.Fields(line)) >= 12:
+				e.Error = errInvalidMnemonic
+			default:
+				e.Error = errUnrecognizedEntry
+			}
+
+			entries = append(entries, e)
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, errNoImportEntries
+	}
+
+	return entries, nil
+}
+
+// jsonEntries collects the secrets in v. A hex string is only taken as a
+// key in a field named like one, it could be a pubkey or an id otherwise.
+// Exports list the npub of their keys, so npubs aren't watched.
+func jsonEntries(entries []*ImportEntry, path string, field string, v any) []*ImportEntry {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			entries = jsonEntries(entries, path+"."+k, k, v[k])
+		}
+	case []any:
+		for i, it := range v {
+			entries = jsonEntries(entries, fmt.Sprintf("%v[%v]", path, i), field, it)
+		}
+	case string:
+		name := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(field))
+
+		if kind := entryKind(v, contains(privateKeyFields, name)); len(kind) > 0 {
+			entries = append(entries, &ImportEntry{Source: path, Kind: kind, Value: strings.TrimSpace(v)})
+		}
+	}
+
+	return entries
+}
+
+func entryKind(s string, hexKey bool) string {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case strings.HasPrefix(s, "nsec1"):
+		return EntryNsec
+	case strings.HasPrefix(s, "ncryptsec1"):
+		return EntryNcryptsec
+	case hexKey && hexKeyRegexp.MatchString(strings.ToLower(s)):
+		return EntryHex
+	case len(strings.Fields(s)) >= 12:
+		if _, ok := MnemonicLanguage(s, ""); ok {
+			return EntryMnemonic
+		}
+	}
+
+	return ""
+}
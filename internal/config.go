@@ -0,0 +1,281 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Duration time.Duration
+
+type Timeouts struct {
+	Connect Duration `yaml:"connect,omitempty"`
+	Query   Duration `yaml:"query,omitempty"`
+	Publish Duration `yaml:"publish,omitempty"`
+}
+
+type Config struct {
+	DB        string              `yaml:"db,omitempty"`
+	Relays    []string            `yaml:"relays,omitempty"`
+	KeyRelays map[string][]string `yaml:"key_relays,omitempty"`
+	Timeouts  Timeouts            `yaml:"timeouts,omitempty"`
+	Archive   bool                `yaml:"archive,omitempty"`
+	Output    string              `yaml:"output,omitempty"`
+
+	CredentialHelper string `yaml:"credential_helper,omitempty"`
+}
+
+var (
+	errUnknownConfigKey = errors.New("Unknown config key")
+)
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(n *yaml.Node) error {
+	v, err := time.ParseDuration(n.Value)
+
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+func XDGConfigHome() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); len(dir) > 0 {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".config"), nil
+}
+
+func XDGDataHome() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); len(dir) > 0 {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+func ConfigPath() (string, error) {
+	dir, err := XDGConfigHome()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "nkcli", "config.yaml"), nil
+}
+
+func LoadConfig(p string) (*Config, error) {
+	conf := new(Config)
+	buf, err := os.ReadFile(p)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return conf, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(buf, conf); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func (c *Config) Save(p string) error {
+	buf, err := yaml.Marshal(c)
+
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(p, buf, 0600)
+}
+
+func (c *Config) Get(key string) (string, error) {
+	switch {
+	case key == "db":
+		return c.DB, nil
+	case key == "relays":
+		return strings.Join(c.Relays, ","), nil
+	case key == "archive":
+		return strconv.FormatBool(c.Archive), nil
+	case key == "output":
+		return c.Output, nil
+	case key == "credential_helper":
+		return c.CredentialHelper, nil
+	case strings.HasPrefix(key, "key_relays."):
+		pub, err := configPubkey(key)
+
+		if err != nil {
+			return "", err
+		}
+
+		return strings.Join(c.KeyRelays[pub], ","), nil
+	case strings.HasPrefix(key, "timeouts."):
+		d, err := c.timeout(key)
+
+		if err != nil {
+			return "", err
+		}
+
+		if *d == 0 {
+			return "", nil
+		}
+
+		return time.Duration(*d).String(), nil
+	}
+
+	return "", errors.Join(errUnknownConfigKey, errors.New(key))
+}
+
+func (c *Config) Set(key string, value string) error {
+	switch {
+	case key == "db":
+		c.DB = value
+	case key == "relays":
+		c.Relays = splitList(value)
+	case key == "archive":
+		if len(value) == 0 {
+			c.Archive = false
+			return nil
+		}
+
+		v, err := strconv.ParseBool(value)
+
+		if err != nil {
+			return err
+		}
+
+		c.Archive = v
+	case key == "output":
+		c.Output = value
+	case key == "credential_helper":
+		c.CredentialHelper = value
+	case strings.HasPrefix(key, "key_relays."):
+		pub, err := configPubkey(key)
+
+		if err != nil {
+			return err
+		}
+
+		if c.KeyRelays == nil {
+			c.KeyRelays = make(map[string][]string)
+		}
+
+		if list := splitList(value); len(list) > 0 {
+			c.KeyRelays[pub] = list
+		} else {
+			delete(c.KeyRelays, pub)
+		}
+	case strings.HasPrefix(key, "timeouts."):
+		d, err := c.timeout(key)
+
+		if err != nil {
+			return err
+		}
+
+		if len(value) == 0 {
+			*d = 0
+			return nil
+		}
+
+		v, err := time.ParseDuration(value)
+
+		if err != nil {
+			return err
+		}
+
+		*d = Duration(v)
+	default:
+		return errors.Join(errUnknownConfigKey, errors.New(key))
+	}
+
+	return nil
+}
+
+func (c *Config) Keys() []string {
+	keys := []string{"db", "relays", "archive", "output", "credential_helper", "timeouts.connect", "timeouts.query", "timeouts.publish"}
+	pubs := make([]string, 0, len(c.KeyRelays))
+
+	for p := range c.KeyRelays {
+		pubs = append(pubs, "key_relays."+p)
+	}
+
+	sort.Strings(pubs)
+
+	return append(keys, pubs...)
+}
+
+func (c *Config) timeout(key string) (*Duration, error) {
+	switch key {
+	case "timeouts.connect":
+		return &c.Timeouts.Connect, nil
+	case "timeouts.query":
+		return &c.Timeouts.Query, nil
+	case "timeouts.publish":
+		return &c.Timeouts.Publish, nil
+	}
+
+	return nil, errors.Join(errUnknownConfigKey, errors.New(key))
+}
+
+// configPubkey reads the npub or hex pubkey of a key_relays key. An nsec is
+// refused, and not echoed: it would be written in the config file.
+func configPubkey(key string) (string, error) {
+	pub := strings.TrimPrefix(key, "key_relays.")
+
+	if strings.HasPrefix(pub, "nsec1") {
+		return "", errInvalidPubkey
+	}
+
+	list := SerializeKeys([]string{pub})
+
+	if len(list) == 0 {
+		return "", errors.Join(errInvalidPubkey, errors.New(key))
+	}
+
+	return list[0], nil
+}
+
+func splitList(s string) []string {
+	result := make([]string, 0)
+
+	for _, it := range strings.Split(s, ",") {
+		if it = strings.TrimSpace(it); len(it) > 0 {
+			result = append(result, it)
+		}
+	}
+
+	return result
+}
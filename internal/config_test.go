@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfigKeyRelaysPubkey(t *testing.T) {
+	const pub = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+	c := new(Config)
+
+	for _, k := range []string{pub, "npub10xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqpkge6d"} {
+		if err := c.Set("key_relays."+k, "wss://relay.example.com"); err != nil {
+			t.Errorf("%v: %v", k, err)
+		}
+	}
+
+	if v, err := c.Get("key_relays." + pub); err != nil || v != "wss://relay.example.com" {
+		t.Errorf("got %q, %v", v, err)
+	}
+
+	// The private key of pub.
+	nsec := "nsec1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqsmhltgl"
+
+	if err := c.Set("key_relays."+nsec, "wss://relay.example.com"); !errors.Is(err, errInvalidPubkey) {
+		t.Errorf("nsec: %v", err)
+	}
+
+	if _, err := c.Get("key_relays." + nsec); !errors.Is(err, errInvalidPubkey) {
+		t.Errorf("nsec: %v", err)
+	}
+
+	for k := range c.KeyRelays {
+		if k != pub {
+			t.Errorf("unexpected key %v", k)
+		}
+	}
+}
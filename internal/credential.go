@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+const (
+	// PurposeUnlock asks for the passphrase of a stored key.
+	PurposeUnlock = "unlock"
+	// PurposeNew asks for a passphrase to protect a new key.
+	PurposeNew = "new"
+)
+
+var (
+	errEmptyHelper = errors.New("Credential helper command is empty")
+)
+
+// CredentialHelper is an external program supplying passphrases, like git's
+// credential helpers. It's called as `<command> get|store|erase` with a
+// CredentialRequest as JSON on stdin, get answers {"passphrase": "..."} on
+// stdout or nothing when it doesn't know the key.
+//
+// A command without path separator is looked up as nkcli-credential-<name>
+// first, so `pass` runs nkcli-credential-pass when it's in PATH.
+type CredentialHelper struct {
+	Command string
+}
+
+type CredentialRequest struct {
+	Pubkey     string `json:"pubkey"`
+	Npub       string `json:"npub"`
+	Purpose    string `json:"purpose,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+type credentialResponse struct {
+	Passphrase string `json:"passphrase"`
+}
+
+func NewCredentialRequest(pub string, purpose string) *CredentialRequest {
+	npub, _ := nip19.EncodePublicKey(pub)
+
+	return &CredentialRequest{Pubkey: pub, Npub: npub, Purpose: purpose}
+}
+
+func (h *CredentialHelper) command(action string) (*exec.Cmd, error) {
+	args := strings.Fields(h.Command)
+
+	if len(args) == 0 {
+		return nil, errEmptyHelper
+	}
+
+	if !strings.ContainsRune(args[0], os.PathSeparator) {
+		if p, err := exec.LookPath("nkcli-credential-" + args[0]); err == nil {
+			args[0] = p
+		}
+	}
+
+	return exec.Command(args[0], append(args[1:], action)...), nil
+}
+
+func (h *CredentialHelper) run(action string, req *CredentialRequest) ([]byte, error) {
+	cmd, err := h.command(action)
+
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := json.Marshal(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(bytes.Buffer)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %v: %w", action, err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// Get returns the passphrase the helper knows for req, nil if it has none.
+func (h *CredentialHelper) Get(req *CredentialRequest) ([]byte, error) {
+	out, err := h.run("get", req)
+
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		return nil, err
+	}
+
+	res := new(credentialResponse)
+
+	if err = json.Unmarshal(out, res); err != nil {
+		return nil, fmt.Errorf("credential helper get: %w", err)
+	}
+
+	if len(res.Passphrase) == 0 {
+		return nil, nil
+	}
+
+	return []byte(res.Passphrase), nil
+}
+
+// Store tells the helper pass worked for req.Pubkey.
+func (h *CredentialHelper) Store(req *CredentialRequest, pass []byte) error {
+	r := *req
+	r.Passphrase = string(pass)
+
+	_, err := h.run("store", &r)
+
+	return err
+}
+
+// Erase tells the helper its passphrase for req.Pubkey is wrong.
+func (h *CredentialHelper) Erase(req *CredentialRequest) error {
+	_, err := h.run("erase", req)
+
+	return err
+}
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/nbd-wtf/go-nostr/nip26"
+)
+
+type DelegationConds struct {
+	Kinds []int
+	Since *time.Time
+	Until *time.Time
+}
+
+type Delegation struct {
+	Delegator  string `json:"delegator"`
+	Delegatee  string `json:"delegatee"`
+	Conditions string `json:"conditions"`
+	Sig        string `json:"sig"`
+	CreatedAt  int64  `json:"created_at"`
+	Issuer     string `json:"issuer"`
+}
+
+var (
+	DefaultMaxDelegationLifetime = 365 * 24 * time.Hour
+)
+
+var (
+	errInvalidCondition    = errors.New("Invalid delegation condition")
+	errDuplicatedCondition = errors.New("Duplicated delegation condition")
+	errInvalidTimeRange    = errors.New("Delegation since must be before until")
+	errDelegationExpired   = errors.New("Delegation until is in the past")
+	errDelegationUnbounded = errors.New("Delegation must have an until bound")
+	errDelegationTooLong   = errors.New("Delegation lifetime exceeds the maximum")
+)
+
+func ParseConditions(s string) (*DelegationConds, error) {
+	conds := new(DelegationConds)
+
+	for _, v := range strings.Split(s, "&") {
+		var field *(*time.Time)
+		var value string
+
+		switch {
+		case len(v) == 0:
+			continue
+		case strings.HasPrefix(v, "kind="):
+			k, err := strconv.Atoi(strings.TrimPrefix(v, "kind="))
+
+			if err != nil || k < 0 {
+				return nil, errors.Join(errInvalidCondition, errors.New(v))
+			}
+
+			conds.Kinds = append(conds.Kinds, k)
+			continue
+		case strings.HasPrefix(v, "created_at>"):
+			field, value = &conds.Since, strings.TrimPrefix(v, "created_at>")
+		case strings.HasPrefix(v, "created_at<"):
+			field, value = &conds.Until, strings.TrimPrefix(v, "created_at<")
+		default:
+			return nil, errors.Join(errInvalidCondition, errors.New(v))
+		}
+
+		if *field != nil {
+			return nil, errors.Join(errDuplicatedCondition, errors.New(v))
+		}
+
+		ts, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil || ts < 0 {
+			return nil, errors.Join(errInvalidCondition, errors.New(v))
+		}
+
+		t := time.Unix(ts, 0)
+		*field = &t
+	}
+
+	if conds.Since != nil && conds.Until != nil && !conds.Since.Before(*conds.Until) {
+		return nil, errInvalidTimeRange
+	}
+
+	return conds, nil
+}
+
+func ParseConditionsMap(obj map[string]any) (*DelegationConds, error) {
+	clauses := make([]string, 0)
+
+	if v, ok := obj["kind"].(float64); ok {
+		clauses = append(clauses, fmt.Sprintf("kind=%d", int(v)))
+	}
+
+	if v, ok := obj["kinds"].([]any); ok {
+		for _, k := range v {
+			n, ok := k.(float64)
+
+			if !ok {
+				return nil, errInvalidCondition
+			}
+
+			clauses = append(clauses, fmt.Sprintf("kind=%d", int(n)))
+		}
+	}
+
+	if v, ok := obj["since"].(float64); ok {
+		clauses = append(clauses, fmt.Sprintf("created_at>%d", int64(v)))
+	}
+
+	if v, ok := obj["until"].(float64); ok {
+		clauses = append(clauses, fmt.Sprintf("created_at<%d", int64(v)))
+	}
+
+	return ParseConditions(strings.Join(clauses, "&"))
+}
+
+func (c *DelegationConds) Check(maxLifetime time.Duration, now time.Time) error {
+	if c.Until != nil && c.Until.Before(now) {
+		return errDelegationExpired
+	}
+
+	if maxLifetime <= 0 {
+		return nil
+	}
+
+	if c.Until == nil {
+		return errDelegationUnbounded
+	}
+
+	start := now
+	if c.Since != nil && c.Since.After(now) {
+		start = *c.Since
+	}
+
+	if c.Until.Sub(start) > maxLifetime {
+		return errDelegationTooLong
+	}
+
+	return nil
+}
+
+func (c *DelegationConds) String() string {
+	s := make([]string, 0)
+
+	if len(c.Kinds) == 0 {
+		s = append(s, "  Kinds: (any)")
+	} else {
+		kinds := make([]string, len(c.Kinds))
+
+		for i, k := range c.Kinds {
+			kinds[i] = strconv.Itoa(k)
+		}
+
+		s = append(s, "  Kinds: "+strings.Join(kinds, ", "))
+	}
+
+	s = append(s, "  Since: "+formatBound(c.Since))
+	s = append(s, "  Until: "+formatBound(c.Until))
+
+	return strings.Join(s, "\n")
+}
+
+func formatBound(t *time.Time) string {
+	if t == nil {
+		return "(unbounded)"
+	}
+
+	return fmt.Sprintf("%v (%v)", t.Unix(), t.Format(time.DateTime))
+}
+
+func CreateDelegation(key *KeyInfo, delegatee string, conds *DelegationConds, issuer string) (*Delegation, error) {
+	d, err := nip26.CreateToken(key.Privkey, delegatee, conds.Kinds, conds.Since, conds.Until)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tag := d.Tag()
+
+	return &Delegation{
+		Delegator:  key.Pubkey,
+		Delegatee:  delegatee,
+		Conditions: tag[2],
+		Sig:        tag[3],
+		CreatedAt:  time.Now().Unix(),
+		Issuer:     issuer,
+	}, nil
+}
+
+func (d *DB) SaveDelegation(del *Delegation) error {
+	key, err := hex.DecodeString(del.Sig)
+
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(del)
+
+	if err != nil {
+		return err
+	}
+
+	return d.saveData(bucketDelegations, key, buf)
+}
+
+func (d *DB) ListDelegations() (list []*Delegation, err error) {
+	list = make([]*Delegation, 0)
+
+	err = d.Db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDelegations).ForEach(func(k, v []byte) error {
+			del := new(Delegation)
+
+			if err := json.Unmarshal(v, del); err != nil {
+				return err
+			}
+
+			list = append(list, del)
+
+			return nil
+		})
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt < list[j].CreatedAt
+	})
+
+	return
+}
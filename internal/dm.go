@@ -0,0 +1,384 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+)
+
+const (
+	KindEncryptedDM = 4
+	KindSeal        = 13
+	KindChatMessage = 14
+	KindGiftWrap    = 1059
+	KindDMRelays    = 10050
+
+	ProtocolNip04 = "nip04"
+	ProtocolNip17 = "nip17"
+
+	// NIP-59 recommends tweaking seal and wrap timestamps up to two days into the past.
+	giftWrapJitter = 2 * 24 * time.Hour
+)
+
+type Message struct {
+	ID        string
+	From      string
+	To        string
+	Text      string
+	CreatedAt time.Time
+	Protocol  string
+}
+
+type rumorEvent struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      nostr.Tags `json:"tags"`
+	Content   string     `json:"content"`
+}
+
+var (
+	errInvalidSeal    = errors.New("Invalid gift wrap seal")
+	errNoRecipient    = errors.New("Message has no recipient")
+	errNotParticipant = errors.New("Message is not addressed to this key")
+)
+
+// Peer returns the other side of the conversation from pub's point of view.
+func (m *Message) Peer(pub string) string {
+	if m.From == pub {
+		return m.To
+	}
+
+	return m.From
+}
+
+func NewNip04Message(key *KeyInfo, to string, text string) (*nostr.Event, error) {
+	shared, err := nip04.ComputeSharedSecret(to, key.Privkey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := nip04.Encrypt(text, shared)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &nostr.Event{
+		PubKey:    key.Pubkey,
+		CreatedAt: time.Now(),
+		Kind:      KindEncryptedDM,
+		Tags:      nostr.Tags{{"p", to}},
+		Content:   content,
+	}
+
+	if err = ev.Sign(key.Privkey); err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+// NewGiftWraps builds a NIP-17 chat message from key to `to` and returns its seal
+// together with one gift wrap per participant, keyed by the recipient pubkey,
+// so the sender can read its own messages back.
+func NewGiftWraps(key *KeyInfo, to string, text string) (*nostr.Event, map[string]*nostr.Event, error) {
+	rumor := &nostr.Event{
+		PubKey:    key.Pubkey,
+		CreatedAt: time.Now(),
+		Kind:      KindChatMessage,
+		Tags:      nostr.Tags{{"p", to}},
+		Content:   text,
+	}
+	rumor.ID = rumor.GetID()
+
+	rumorJSON, err := json.Marshal(&rumorEvent{
+		ID:        rumor.ID,
+		PubKey:    rumor.PubKey,
+		CreatedAt: rumor.CreatedAt.Unix(),
+		Kind:      rumor.Kind,
+		Tags:      rumor.Tags,
+		Content:   rumor.Content,
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wraps := make(map[string]*nostr.Event)
+	recipients := []string{to}
+	var seal *nostr.Event
+
+	if to != key.Pubkey {
+		recipients = append(recipients, key.Pubkey)
+	}
+
+	for _, p := range recipients {
+		ck, err := Nip44ConversationKey(key.Privkey, p)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		content, err := Nip44Encrypt(string(rumorJSON), ck)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		s := &nostr.Event{
+			PubKey:    key.Pubkey,
+			CreatedAt: jitterTime(),
+			Kind:      KindSeal,
+			Tags:      nostr.Tags{},
+			Content:   content,
+		}
+
+		if err = s.Sign(key.Privkey); err != nil {
+			return nil, nil, err
+		}
+
+		if seal == nil {
+			seal = s
+		}
+
+		if wraps[p], err = giftWrap(s, p); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return seal, wraps, nil
+}
+
+func giftWrap(seal *nostr.Event, to string) (*nostr.Event, error) {
+	sk := newPrivateKey()
+	pub, err := nostr.GetPublicKey(sk)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ck, err := Nip44ConversationKey(sk, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := json.Marshal(seal)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := Nip44Encrypt(string(buf), ck)
+
+	if err != nil {
+		return nil, err
+	}
+
+	wrap := &nostr.Event{
+		PubKey:    pub,
+		CreatedAt: jitterTime(),
+		Kind:      KindGiftWrap,
+		Tags:      nostr.Tags{{"p", to}},
+		Content:   content,
+	}
+
+	if err = wrap.Sign(sk); err != nil {
+		return nil, err
+	}
+
+	return wrap, nil
+}
+
+func jitterTime() time.Time {
+	return time.Now().Add(-time.Duration(rand.Int63n(int64(giftWrapJitter))))
+}
+
+func nip44DecryptEvent(sk string, pub string, payload string) (*nostr.Event, error) {
+	ck, err := Nip44ConversationKey(sk, pub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := Nip44Decrypt(payload, ck)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ev := new(nostr.Event)
+
+	if err = json.Unmarshal([]byte(text), ev); err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+func UnwrapMessage(key *KeyInfo, wrap *nostr.Event) (*Message, error) {
+	seal, err := nip44DecryptEvent(key.Privkey, wrap.PubKey, wrap.Content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, _ := seal.CheckSignature(); !ok || seal.Kind != KindSeal {
+		return nil, errInvalidSeal
+	}
+
+	rumor, err := nip44DecryptEvent(key.Privkey, seal.PubKey, seal.Content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rumor.Kind != KindChatMessage || rumor.PubKey != seal.PubKey {
+		return nil, errInvalidSeal
+	}
+
+	to := firstTag(rumor.Tags, "p")
+
+	if len(to) == 0 {
+		return nil, errNoRecipient
+	}
+
+	return &Message{
+		ID:        rumor.GetID(),
+		From:      rumor.PubKey,
+		To:        to,
+		Text:      rumor.Content,
+		CreatedAt: rumor.CreatedAt,
+		Protocol:  ProtocolNip17,
+	}, nil
+}
+
+func DecryptNip04Message(key *KeyInfo, ev *nostr.Event) (*Message, error) {
+	to := firstTag(ev.Tags, "p")
+
+	if len(to) == 0 {
+		return nil, errNoRecipient
+	}
+
+	msg := &Message{
+		ID:        ev.ID,
+		From:      ev.PubKey,
+		To:        to,
+		CreatedAt: ev.CreatedAt,
+		Protocol:  ProtocolNip04,
+	}
+
+	if msg.From != key.Pubkey && msg.To != key.Pubkey {
+		return nil, errNotParticipant
+	}
+
+	shared, err := nip04.ComputeSharedSecret(msg.Peer(key.Pubkey), key.Privkey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Text, err = nip04.Decrypt(ev.Content, shared); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func firstTag(tags nostr.Tags, name string) string {
+	for _, t := range tags {
+		if len(t) > 1 && t[0] == name {
+			return t[1]
+		}
+	}
+
+	return ""
+}
+
+// DMRelays returns where pub wants to receive NIP-17 messages: its kind 10050
+// list, falling back to its inbox relays and then the boot relays.
+func (r *Router) DMRelays(ctx context.Context, pub string) []string {
+	r.discover(ctx, []string{pub})
+
+	if ev := r.FetchLatest(ctx, nostr.Filter{Kinds: []int{KindDMRelays}, Authors: []string{pub}}); ev != nil {
+		list := make([]string, 0)
+
+		for _, t := range ev.Tags {
+			if len(t) > 1 && t[0] == "relay" {
+				list = appendUnique(list, t[1])
+			}
+		}
+
+		if len(list) > 0 {
+			return r.rank(list)
+		}
+	}
+
+	if list := r.InboxRelays(pub); len(list) > 0 {
+		return list
+	}
+
+	return r.rank(r.Boots)
+}
+
+// FetchMessages loads and decrypts the direct messages of key, optionally only
+// those exchanged with peer, oldest first. Undecryptable events are counted in failed.
+func (r *Router) FetchMessages(ctx context.Context, key *KeyInfo, peer string, since *time.Time, limit int) (list []*Message, failed int) {
+	me := key.Pubkey
+	relays := appendUnique(r.DMRelays(ctx, me), r.OutboxRelays(me)...)
+
+	sent := nostr.Filter{Kinds: []int{KindEncryptedDM}, Authors: []string{me}, Since: since, Limit: limit}
+	received := nostr.Filter{Kinds: []int{KindEncryptedDM}, Tags: nostr.TagMap{"p": {me}}, Since: since, Limit: limit}
+	wraps := nostr.Filter{Kinds: []int{KindGiftWrap}, Tags: nostr.TagMap{"p": {me}}, Limit: limit}
+
+	if len(peer) > 0 {
+		sent.Tags = nostr.TagMap{"p": {peer}}
+		received.Authors = []string{peer}
+	}
+
+	if since != nil {
+		// wrap timestamps are randomized into the past
+		s := since.Add(-giftWrapJitter)
+		wraps.Since = &s
+	}
+
+	seen := make(map[string]bool)
+
+	for _, ev := range r.Query(ctx, relays, nostr.Filters{sent, received, wraps}) {
+		var msg *Message
+		var err error
+
+		if ev.Kind == KindGiftWrap {
+			msg, err = UnwrapMessage(key, ev)
+		} else {
+			msg, err = DecryptNip04Message(key, ev)
+		}
+
+		if err != nil {
+			failed++
+			continue
+		}
+
+		if seen[msg.ID] || (len(peer) > 0 && msg.Peer(me) != peer) || (since != nil && msg.CreatedAt.Before(*since)) {
+			continue
+		}
+
+		seen[msg.ID] = true
+		list = append(list, msg)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	return
+}
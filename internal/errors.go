@@ -0,0 +1,56 @@
+package internal
+
+import "errors"
+
+// Error codes reported in structured output, each maps to an exit code.
+const (
+	CodeError    = "error"
+	CodeUsage    = "usage"
+	CodeNotFound = "not_found"
+	CodeAuth     = "auth"
+	CodeRejected = "rejected"
+	CodeNetwork  = "network"
+)
+
+var ExitCodes = map[string]int{
+	CodeError:    1,
+	CodeUsage:    2,
+	CodeNotFound: 3,
+	CodeAuth:     4,
+	CodeRejected: 5,
+	CodeNetwork:  6,
+}
+
+type ErrorClass struct {
+	Code string
+	Errs []error
+}
+
+var errorClasses = []ErrorClass{
+	{Code: CodeNotFound, Errs: []error{errDataNotFound, errKeyNotFound, errConnNotFound, errEventNotFound, errNip05NotFound, errSeedNotFound}},
+	{Code: CodeAuth, Errs: []error{errInvalidPassphrase, errSeedPassphrase, errNcryptsecPassword}},
+	{Code: CodeRejected, Errs: []error{errUserRejected, errWatchOnly, errPublishFailed, errDelegationExpired, errDelegationUnbounded, errDelegationTooLong}},
+	{Code: CodeNetwork, Errs: []error{errRelayTimeout, errNip05Unreachable, errNoRelayAnswered}},
+	{Code: CodeUsage, Errs: []error{
+		errInvalidScheme, errInvalidPubkey, errInvalidRelay, errInvalidMetadata, errInvalidEventField,
+		errUnknownConfigKey, errInvalidCondition, errDuplicatedCondition, errInvalidTimeRange,
+		errInvalidKind, errInvalidNip05, errNotTerminal, errInvalidWordCount, errUnknownLanguage, errInvalidAccount,
+		errInvalidMnemonic, errInvalidThreshold, errInvalidShare, errShareChecksum, errShareMismatch,
+		errDuplicateShare, errNotEnoughShares, errInvalidVanity, errVanityTooLong, errInvalidPoW,
+		errInvalidNcryptsec, errNoImportEntries,
+	}},
+}
+
+// ClassifyError returns the error code of err, using extra classes before the
+// package's own ones.
+func ClassifyError(err error, extra ...ErrorClass) string {
+	for _, class := range append(extra, errorClasses...) {
+		for _, e := range class.Errs {
+			if errors.Is(err, e) {
+				return class.Code
+			}
+		}
+	}
+
+	return CodeError
+}
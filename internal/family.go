@@ -0,0 +1,233 @@
+package internal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// MaxAccount is the highest NIP-06 account, it's a hardened index.
+const MaxAccount = bip32.FirstHardenedChild - 1
+
+// MaxAccountRange is how many accounts one range derives at most.
+const MaxAccountRange = 1000
+
+// Derivation tells which seed a key was derived from, keys sharing the
+// fingerprint of the BIP-32 master key are one family.
+type Derivation struct {
+	Fingerprint string `json:"fingerprint"`
+	Account     uint32 `json:"account"`
+}
+
+// Seed is a stored mnemonic, encrypted with the passphrase of Pubkey.
+type Seed struct {
+	Fingerprint string `json:"fingerprint"`
+	Pubkey      string `json:"pubkey"`
+	Mnemonic    []byte `json:"mnemonic"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+var (
+	errInvalidAccount = errors.New("Invalid account, use N or N-M of at most 1000 accounts")
+	errSeedNotFound   = errors.New("Seed not found")
+	errSeedPassphrase = errors.New("Mnemonic passphrase doesn't match the seed")
+)
+
+func (d *Derivation) Path() string {
+	return fmt.Sprintf("m/44'/1237'/%v'/0/0", d.Account)
+}
+
+// DeriveKey derives the private key of account at m/44'/1237'/<account>'/0/0
+// as NIP-06 describes, account 0 is what nip06.PrivateKeyFromSeed returns.
+func DeriveKey(seed []byte, account uint32) (string, *Derivation, error) {
+	if account > MaxAccount {
+		return "", nil, errors.Join(errInvalidAccount, fmt.Errorf("%v", account))
+	}
+
+	master, err := bip32.NewMasterKey(seed)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	path := []uint32{
+		bip32.FirstHardenedChild + 44,
+		bip32.FirstHardenedChild + 1237,
+		bip32.FirstHardenedChild + account,
+		0,
+		0,
+	}
+
+	next := master
+	d := &Derivation{Account: account}
+
+	for _, idx := range path {
+		if next, err = next.NewChildKey(idx); err != nil {
+			return "", nil, err
+		}
+
+		// The first child keeps the fingerprint of the master key.
+		if len(d.Fingerprint) == 0 {
+			d.Fingerprint = hex.EncodeToString(next.FingerPrint)
+		}
+	}
+
+	return hex.EncodeToString(next.Key), d, nil
+}
+
+// ParseAccounts parses an account N or an inclusive range N-M of at most
+// MaxAccountRange accounts.
+func ParseAccounts(s string) ([]uint32, error) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(s), "-")
+
+	if !isRange {
+		to = from
+	}
+
+	a, err := strconv.ParseUint(from, 10, 32)
+
+	if err != nil {
+		return nil, errors.Join(errInvalidAccount, errors.New(s))
+	}
+
+	b, err := strconv.ParseUint(to, 10, 32)
+
+	if err != nil || b < a || b > uint64(MaxAccount) || b-a >= MaxAccountRange {
+		return nil, errors.Join(errInvalidAccount, errors.New(s))
+	}
+
+	list := make([]uint32, 0, b-a+1)
+
+	for i := a; i <= b; i++ {
+		list = append(list, uint32(i))
+	}
+
+	return list, nil
+}
+
+func (d *DB) SaveDerivation(pub string, dv *Derivation) error {
+	key, err := hex.DecodeString(pub)
+
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(dv)
+
+	if err != nil {
+		return err
+	}
+
+	return d.saveData(bucketDerivations, key, buf)
+}
+
+func derivation(tx *bolt.Tx, key []byte) *Derivation {
+	buf := tx.Bucket(bucketDerivations).Get(key)
+
+	if buf == nil {
+		return nil
+	}
+
+	dv := new(Derivation)
+
+	if err := json.Unmarshal(buf, dv); err != nil {
+		return nil
+	}
+
+	return dv
+}
+
+func (d *DB) SaveSeed(fingerprint string, pub string, mnemonic string, pass []byte) error {
+	enc, err := Encrypt([]byte(mnemonic), pass)
+
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(&Seed{Fingerprint: fingerprint, Pubkey: pub, Mnemonic: enc, CreatedAt: time.Now().Unix()})
+
+	if err != nil {
+		return err
+	}
+
+	return d.saveData(bucketSeeds, []byte(fingerprint), buf)
+}
+
+func (d *DB) GetSeed(fingerprint string) (*Seed, error) {
+	seed := new(Seed)
+
+	err := d.Db.View(func(tx *bolt.Tx) error {
+		buf := tx.Bucket(bucketSeeds).Get([]byte(strings.ToLower(fingerprint)))
+
+		if buf == nil {
+			return errors.Join(errSeedNotFound, errors.New(fingerprint))
+		}
+
+		return json.Unmarshal(buf, seed)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return seed, nil
+}
+
+func (d *DB) ListSeeds() (list []*Seed, err error) {
+	list = make([]*Seed, 0)
+
+	err = d.Db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSeeds).ForEach(func(k, v []byte) error {
+			seed := new(Seed)
+
+			if err := json.Unmarshal(v, seed); err != nil {
+				return err
+			}
+
+			list = append(list, seed)
+
+			return nil
+		})
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt < list[j].CreatedAt
+	})
+
+	return
+}
+
+// Unlock decrypts the mnemonic of s with pass.
+func (s *Seed) Unlock(pass []byte) (string, error) {
+	buf, err := Decrypt(s.Mnemonic, pass)
+
+	if err != nil {
+		return "", errInvalidPassphrase
+	}
+
+	return string(buf), nil
+}
+
+// CheckSeed makes sure seed, derived with a BIP-39 passphrase, is the one
+// of fingerprint.
+func CheckSeed(seed []byte, fingerprint string) error {
+	_, d, err := DeriveKey(seed, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(d.Fingerprint, fingerprint) {
+		return errSeedPassphrase
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/text/unicode/norm"
+)
+
+const DefaultLanguage = "english"
+
+// MnemonicLanguages are the BIP-39 wordlists by name.
+var MnemonicLanguages = map[string][]string{
+	"english":             wordlists.English,
+	"japanese":            wordlists.Japanese,
+	"korean":              wordlists.Korean,
+	"spanish":             wordlists.Spanish,
+	"chinese-simplified":  wordlists.ChineseSimplified,
+	"chinese-traditional": wordlists.ChineseTraditional,
+	"french":              wordlists.French,
+	"italian":             wordlists.Italian,
+	"czech":               wordlists.Czech,
+}
+
+// languageIDs keeps the languages in a fixed order to write them in bytes.
+var languageIDs = []string{
+	"english", "japanese", "korean", "spanish", "chinese-simplified",
+	"chinese-traditional", "french", "italian", "czech",
+}
+
+var (
+	errInvalidWordCount = errors.New("Invalid word count, use 12, 15, 18, 21 or 24")
+	errUnknownLanguage  = errors.New("Unknown mnemonic language")
+	errInvalidMnemonic  = errors.New("Invalid mnemonic words")
+)
+
+func LanguageNames() []string {
+	names := make([]string, 0, len(MnemonicLanguages))
+
+	for n := range MnemonicLanguages {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// withWordList runs fn with the normalized wordlist of language selected in
+// go-bip39, which only keeps one global list, and restores English after.
+func withWordList(language string, fn func() error) error {
+	list, ok := MnemonicLanguages[language]
+
+	if !ok {
+		return errors.Join(errUnknownLanguage, errors.New(language))
+	}
+
+	normalized := make([]string, len(list))
+
+	for i, w := range list {
+		normalized[i] = norm.NFKD.String(w)
+	}
+
+	bip39.SetWordList(normalized)
+	defer bip39.SetWordList(wordlists.English)
+
+	return fn()
+}
+
+// NewMnemonic generates a mnemonic of count words, 12 to 24 in steps of 3.
+func NewMnemonic(count int, language string) (mnemonic string, err error) {
+	if count < 12 || count > 24 || count%3 != 0 {
+		return "", errInvalidWordCount
+	}
+
+	err = withWordList(language, func() error {
+		ent, err := bip39.NewEntropy(count / 3 * 32)
+
+		if err != nil {
+			return err
+		}
+
+		mnemonic, err = bip39.NewMnemonic(ent)
+
+		return err
+	})
+
+	return
+}
+
+// MnemonicLanguage checks mnemonic against the wordlist of language, or every
+// wordlist when language is empty, and returns the one it belongs to.
+func MnemonicLanguage(mnemonic string, language string) (string, bool) {
+	mnemonic = NormalizeMnemonic(mnemonic)
+	languages := []string{language}
+
+	if len(language) == 0 {
+		languages = LanguageNames()
+	}
+
+	for _, l := range languages {
+		if withWordList(l, func() error {
+			_, err := bip39.EntropyFromMnemonic(mnemonic)
+			return err
+		}) == nil {
+			return l, true
+		}
+	}
+
+	return "", false
+}
+
+// NormalizeMnemonic applies the NFKD form BIP-39 requires and joins the
+// words with single spaces.
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+}
+
+// SeedFromWords is nip06.SeedFromWords with the optional BIP-39 passphrase,
+// both normalized so non-English mnemonics derive the same seed as other
+// wallets.
+func SeedFromWords(mnemonic string, passphrase string) []byte {
+	return bip39.NewSeed(NormalizeMnemonic(mnemonic), norm.NFKD.String(passphrase))
+}
+
+// MnemonicEntropy returns the entropy of mnemonic and its language.
+func MnemonicEntropy(mnemonic string) (entropy []byte, language string, err error) {
+	language, ok := MnemonicLanguage(mnemonic, "")
+
+	if !ok {
+		return nil, "", errInvalidMnemonic
+	}
+
+	err = withWordList(language, func() error {
+		entropy, err = bip39.EntropyFromMnemonic(NormalizeMnemonic(mnemonic))
+		return err
+	})
+
+	return
+}
+
+func MnemonicFromEntropy(entropy []byte, language string) (mnemonic string, err error) {
+	err = withWordList(language, func() error {
+		mnemonic, err = bip39.NewMnemonic(entropy)
+		return err
+	})
+
+	return
+}
+
+func languageID(language string) int {
+	for i, l := range languageIDs {
+		if l == language {
+			return i
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+const ncryptsecVersion = 2
+
+var (
+	errInvalidNcryptsec  = errors.New("Invalid ncryptsec")
+	errNcryptsecPassword = errors.New("Wrong ncryptsec password")
+)
+
+// DecryptNcryptsec decrypts a NIP-49 ncryptsec1 key with password and
+// returns the hex private key.
+func DecryptNcryptsec(s string, password string) (string, error) {
+	hrp, data, err := decodeBech32(s)
+
+	if err != nil || hrp != "ncryptsec" {
+		return "", errInvalidNcryptsec
+	}
+
+	// version, log_n, salt, nonce, key security byte and the sealed key.
+	if len(data) != 1+1+16+24+1+48 || data[0] != ncryptsecVersion || data[1] > 22 {
+		return "", errInvalidNcryptsec
+	}
+
+	salt, nonce, ad, sealed := data[2:18], data[18:42], data[42:43], data[43:]
+	key, err := scrypt.Key([]byte(norm.NFKC.String(password)), salt, 1<<data[1], 8, 1, 32)
+
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	priv, err := aead.Open(nil, nonce, sealed, ad)
+
+	if err != nil {
+		return "", errNcryptsecPassword
+	}
+
+	return hex.EncodeToString(priv), nil
+}
+
+// decodeBech32 decodes s into its prefix and 8 bit data. Unlike nip19 it
+// doesn't look at the prefix, ncryptsec is longer than bech32 allows anyway.
+func decodeBech32(s string) (string, []byte, error) {
+	s = strings.ToLower(s)
+	one := strings.LastIndexByte(s, '1')
+
+	if one < 1 || one+7 > len(s) {
+		return "", nil, errInvalidNcryptsec
+	}
+
+	hrp := s[:one]
+	values := make([]byte, 0, len(s)-one-1)
+
+	for _, ch := range s[one+1:] {
+		v := strings.IndexRune(Bech32Charset, ch)
+
+		if v < 0 {
+			return "", nil, errInvalidNcryptsec
+		}
+
+		values = append(values, byte(v))
+	}
+
+	if bech32Polymod(hrp, values) != 1 {
+		return "", nil, errInvalidNcryptsec
+	}
+
+	// Regroup 5 bit values to bytes, dropping the checksum and padding.
+	values = values[:len(values)-6]
+	data := make([]byte, 0, len(values)*5/8)
+	acc, n := 0, 0
+
+	for _, v := range values {
+		acc = acc<<5 | int(v)
+		n += 5
+
+		if n >= 8 {
+			n -= 8
+			data = append(data, byte(acc>>n))
+			acc &= 1<<n - 1
+		}
+	}
+
+	if n >= 5 || acc != 0 {
+		return "", nil, errInvalidNcryptsec
+	}
+
+	return hrp, data, nil
+}
+
+func bech32Polymod(hrp string, values []byte) int {
+	gen := []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := 1
+	expanded := make([]byte, 0, len(hrp)*2+1+len(values))
+
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+
+	expanded = append(expanded, 0)
+
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+
+	for _, v := range append(expanded, values...) {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ int(v)
+
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+
+	return chk
+}
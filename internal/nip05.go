@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Nip05Verifier resolves a NIP-05 identifier to the pubkey it points to.
+type Nip05Verifier interface {
+	Resolve(ctx context.Context, identifier string) (string, error)
+}
+
+// HTTPVerifier resolves identifiers through /.well-known/nostr.json. Scheme
+// and Client can be swapped to point it at a local stand-in.
+type HTTPVerifier struct {
+	Client *http.Client
+	Scheme string
+}
+
+type Nip05Status struct {
+	Identifier string `json:"identifier"`
+	Verified   bool   `json:"verified"`
+	CheckedAt  int64  `json:"checked_at"`
+	Error      string `json:"error,omitempty"`
+	Temporary  bool   `json:"temporary,omitempty"`
+}
+
+var (
+	Nip05CacheTTL = 24 * time.Hour
+	// Nip05RetryTTL is how long a failure to reach the server is cached.
+	Nip05RetryTTL = 10 * time.Minute
+)
+
+var (
+	errInvalidNip05     = errors.New("Invalid NIP-05 identifier")
+	errNip05NotFound    = errors.New("NIP-05 name not found")
+	errNip05Mismatch    = errors.New("NIP-05 points to another pubkey")
+	errNip05Redirect    = errors.New("NIP-05 server redirected, redirects are ignored")
+	errNip05Unreachable = errors.New("NIP-05 server unreachable")
+)
+
+// NewHTTPVerifier returns a verifier that doesn't follow redirects, NIP-05
+// fetchers must ignore them.
+func NewHTTPVerifier() *HTTPVerifier {
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &HTTPVerifier{Client: client, Scheme: "https"}
+}
+
+func (v *HTTPVerifier) Resolve(ctx context.Context, identifier string) (string, error) {
+	name, domain, err := splitNip05(identifier)
+
+	if err != nil {
+		return "", err
+	}
+
+	u := url.URL{
+		Scheme:   v.Scheme,
+		Host:     domain,
+		Path:     "/.well-known/nostr.json",
+		RawQuery: url.Values{"name": {name}}.Encode(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := v.Client.Do(req)
+
+	if err != nil {
+		return "", errors.Join(errNip05Unreachable, err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return "", errNip05Redirect
+	}
+
+	if resp.StatusCode >= 500 {
+		return "", errors.Join(errNip05Unreachable, fmt.Errorf("%v returned %v", u.Host, resp.Status))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%v returned %v", u.Host, resp.Status)
+	}
+
+	var body struct {
+		Names map[string]string `json:"names"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	pub, ok := body.Names[name]
+
+	if !ok {
+		return "", errNip05NotFound
+	}
+
+	return strings.ToLower(pub), nil
+}
+
+func splitNip05(identifier string) (string, string, error) {
+	name, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(identifier)), "@")
+
+	if !ok {
+		name, domain = "_", name
+	}
+
+	if len(name) == 0 || len(domain) == 0 || strings.ContainsAny(domain, "/?#@") {
+		return "", "", errors.Join(errInvalidNip05, errors.New(identifier))
+	}
+
+	return name, domain, nil
+}
+
+func VerifyNip05(ctx context.Context, v Nip05Verifier, pub string, identifier string) *Nip05Status {
+	status := &Nip05Status{Identifier: identifier, CheckedAt: time.Now().Unix()}
+	resolved, err := v.Resolve(ctx, identifier)
+
+	if err == nil && resolved != pub {
+		err = errNip05Mismatch
+	}
+
+	if err != nil {
+		status.Error = err.Error()
+		status.Temporary = errors.Is(err, errNip05Unreachable)
+	} else {
+		status.Verified = true
+	}
+
+	return status
+}
+
+// Stale reports whether the status must be checked again for identifier.
+// Failures to reach the server are retried sooner than answers.
+func (s *Nip05Status) Stale(identifier string, now time.Time) bool {
+	if s == nil || s.Identifier != identifier {
+		return true
+	}
+
+	ttl := Nip05CacheTTL
+
+	if s.Temporary {
+		ttl = Nip05RetryTTL
+	}
+
+	return now.Sub(time.Unix(s.CheckedAt, 0)) > ttl
+}
+
+// VerifyKeys checks the NIP-05 of every key whose cached status is stale, or
+// all of them when force is set, and stores the results.
+func (d *DB) VerifyKeys(ctx context.Context, v Nip05Verifier, keys []*KeyInfo, force bool) []*KeyInfo {
+	now := time.Now()
+	checked := make([]*KeyInfo, 0)
+	wg := new(sync.WaitGroup)
+
+	for _, k := range keys {
+		if k.Metadata == nil || len(k.Metadata.Nip05) == 0 || (!force && !k.Nip05.Stale(k.Metadata.Nip05, now)) {
+			continue
+		}
+
+		checked = append(checked, k)
+		wg.Add(1)
+
+		go func(k *KeyInfo) {
+			defer wg.Done()
+
+			k.Nip05 = VerifyNip05(ctx, v, k.Pubkey, k.Metadata.Nip05)
+		}(k)
+	}
+
+	wg.Wait()
+
+	for _, k := range checked {
+		d.SaveNip05(k.Pubkey, k.Nip05)
+	}
+
+	return checked
+}
+
+func (d *DB) SaveNip05(pub string, s *Nip05Status) error {
+	key, err := hex.DecodeString(pub)
+
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(s)
+
+	if err != nil {
+		return err
+	}
+
+	return d.saveData(bucketNip05, key, buf)
+}
+
+func nip05Status(tx *bolt.Tx, key []byte) *Nip05Status {
+	buf := tx.Bucket(bucketNip05).Get(key)
+
+	if buf == nil {
+		return nil
+	}
+
+	s := new(Nip05Status)
+
+	if err := json.Unmarshal(buf, s); err != nil {
+		return nil
+	}
+
+	return s
+}
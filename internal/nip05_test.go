@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyNip05(t *testing.T) {
+	const pub = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") == "moved" {
+			http.Redirect(w, r, "/.well-known/nostr.json?name=bob", http.StatusFound)
+			return
+		}
+
+		w.Write([]byte(`{"names":{"bob":"` + pub + `","alice":"` + strings.Repeat("0", 64) + `"}}`))
+	}))
+	defer srv.Close()
+
+	v := NewHTTPVerifier()
+	v.Scheme = "http"
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"bob", nil},
+		{"alice", errNip05Mismatch},
+		{"moved", errNip05Redirect},
+		{"carol", errNip05NotFound},
+	}
+
+	for _, tt := range tests {
+		s := VerifyNip05(context.Background(), v, pub, tt.name+"@"+host)
+
+		if s.Verified != (tt.err == nil) || (tt.err != nil && s.Error != tt.err.Error()) {
+			t.Errorf("%v: verified %v, error %q", tt.name, s.Verified, s.Error)
+		}
+
+		if s.Temporary {
+			t.Errorf("%v: answer cached as temporary", tt.name)
+		}
+	}
+}
+
+func TestVerifyNip05Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	host := strings.TrimPrefix(srv.URL, "http://")
+	srv.Close()
+
+	v := NewHTTPVerifier()
+	v.Scheme = "http"
+	s := VerifyNip05(context.Background(), v, "", "bob@"+host)
+
+	if s.Verified || !s.Temporary {
+		t.Fatalf("verified %v, temporary %v", s.Verified, s.Temporary)
+	}
+
+	if _, err := v.Resolve(context.Background(), "bob@"+host); !errors.Is(err, errNip05Unreachable) {
+		t.Errorf("error %v", err)
+	}
+
+	now := time.Unix(s.CheckedAt, 0)
+
+	if s.Stale(s.Identifier, now.Add(time.Minute)) || !s.Stale(s.Identifier, now.Add(Nip05RetryTTL+time.Second)) {
+		t.Error("unreachable status isn't retried after Nip05RetryTTL")
+	}
+}
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	nip44Version    = 2
+	nip44MinPlain   = 1
+	nip44MaxPlain   = 65535
+	nip44MinPayload = 132
+	nip44MaxPayload = 87472
+)
+
+var (
+	errNip44Version = errors.New("Unsupported NIP-44 version")
+	errNip44Payload = errors.New("Invalid NIP-44 payload")
+	errNip44Length  = errors.New("NIP-44 plaintext must be 1 to 65535 bytes")
+	errNip44Mac     = errors.New("NIP-44 MAC mismatch")
+)
+
+// Nip44ConversationKey derives the NIP-44 v2 conversation key between sk and pub (both hex).
+func Nip44ConversationKey(sk string, pub string) ([]byte, error) {
+	shared, err := nip04.ComputeSharedSecret(pub, sk)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hkdf.Extract(sha256.New, shared, []byte("nip44-v2")), nil
+}
+
+func Nip44Encrypt(text string, key []byte) (string, error) {
+	nonce := make([]byte, 32)
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	return nip44Encrypt(text, key, nonce)
+}
+
+func nip44Encrypt(text string, key []byte, nonce []byte) (string, error) {
+	cipherKey, cipherNonce, macKey, err := nip44MessageKeys(key, nonce)
+
+	if err != nil {
+		return "", err
+	}
+
+	padded, err := nip44Pad(text)
+
+	if err != nil {
+		return "", err
+	}
+
+	c, err := chacha20.NewUnauthenticatedCipher(cipherKey, cipherNonce)
+
+	if err != nil {
+		return "", err
+	}
+
+	c.XORKeyStream(padded, padded)
+
+	payload := make([]byte, 0, 1+len(nonce)+len(padded)+sha256.Size)
+	payload = append(payload, nip44Version)
+	payload = append(payload, nonce...)
+	payload = append(payload, padded...)
+	payload = append(payload, nip44Mac(macKey, nonce, padded)...)
+
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+func Nip44Decrypt(payload string, key []byte) (string, error) {
+	if len(payload) == 0 || payload[0] == '#' {
+		return "", errNip44Version
+	}
+
+	if len(payload) < nip44MinPayload || len(payload) > nip44MaxPayload {
+		return "", errNip44Payload
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+
+	if err != nil {
+		return "", errors.Join(errNip44Payload, err)
+	}
+
+	if len(data) < 99 || len(data) > 65603 {
+		return "", errNip44Payload
+	}
+
+	if data[0] != nip44Version {
+		return "", errNip44Version
+	}
+
+	nonce := data[1:33]
+	ciphertext := data[33 : len(data)-sha256.Size]
+	mac := data[len(data)-sha256.Size:]
+
+	cipherKey, cipherNonce, macKey, err := nip44MessageKeys(key, nonce)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !hmac.Equal(mac, nip44Mac(macKey, nonce, ciphertext)) {
+		return "", errNip44Mac
+	}
+
+	c, err := chacha20.NewUnauthenticatedCipher(cipherKey, cipherNonce)
+
+	if err != nil {
+		return "", err
+	}
+
+	padded := make([]byte, len(ciphertext))
+	c.XORKeyStream(padded, ciphertext)
+
+	return nip44Unpad(padded)
+}
+
+func nip44MessageKeys(key []byte, nonce []byte) ([]byte, []byte, []byte, error) {
+	if len(key) != 32 || len(nonce) != 32 {
+		return nil, nil, nil, errNip44Payload
+	}
+
+	buf := make([]byte, 76)
+
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, key, nonce), buf); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return buf[:32], buf[32:44], buf[44:], nil
+}
+
+func nip44Mac(key []byte, nonce []byte, ciphertext []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(nonce)
+	h.Write(ciphertext)
+
+	return h.Sum(nil)
+}
+
+func nip44PaddedLen(n int) int {
+	if n <= 32 {
+		return 32
+	}
+
+	next := 1 << bits.Len(uint(n-1))
+	chunk := 32
+
+	if next > 256 {
+		chunk = next / 8
+	}
+
+	return chunk * ((n-1)/chunk + 1)
+}
+
+func nip44Pad(text string) ([]byte, error) {
+	n := len(text)
+
+	if n < nip44MinPlain || n > nip44MaxPlain {
+		return nil, errNip44Length
+	}
+
+	buf := make([]byte, 2+nip44PaddedLen(n))
+	binary.BigEndian.PutUint16(buf, uint16(n))
+	copy(buf[2:], text)
+
+	return buf, nil
+}
+
+func nip44Unpad(padded []byte) (string, error) {
+	if len(padded) < 2 {
+		return "", errNip44Payload
+	}
+
+	n := int(binary.BigEndian.Uint16(padded))
+
+	if n < nip44MinPlain || len(padded) != 2+nip44PaddedLen(n) {
+		return "", errNip44Payload
+	}
+
+	return string(padded[2 : 2+n]), nil
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Vectors from the NIP-44 v2 specification.
+func TestNip44ConversationKey(t *testing.T) {
+	key, err := Nip44ConversationKey("315e59ff51cb9209768cf7da80791ddcaae56ac9775eb25b6dee1234bc5d2268", "c2f9d9948dc8c7c38321e4b85c8558872eafa0641cd269db76848a6073e69133")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hex.EncodeToString(key); got != "3dfef0ce2a4d80a25e7a328accf73448ef67096f65f79588e358d9a0eb9013f1" {
+		t.Errorf("conversation key = %v", got)
+	}
+}
+
+func TestNip44EncryptDecrypt(t *testing.T) {
+	sec1 := "0000000000000000000000000000000000000000000000000000000000000001"
+	sec2 := "0000000000000000000000000000000000000000000000000000000000000002"
+	payload := "AgAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABee0G5VSK0/9YypIObAtDKfYEAjD35uVkHyB0F4DwrcNaCXlCWZKaArsGrY6M9wnuTMxWfp1RTN9Xga8no+kF5Vsb"
+
+	pub2, err := nostr.GetPublicKey(sec2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := Nip44ConversationKey(sec1, pub2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hex.EncodeToString(key); got != "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d" {
+		t.Fatalf("conversation key = %v", got)
+	}
+
+	nonce, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	got, err := nip44Encrypt("a", key, nonce)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != payload {
+		t.Errorf("payload = %v", got)
+	}
+
+	text, err := Nip44Decrypt(payload, key)
+
+	if err != nil || text != "a" {
+		t.Errorf("decrypt = %q, %v", text, err)
+	}
+}
@@ -1,9 +1,13 @@
 package internal
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/boltdb/bolt"
@@ -15,6 +19,10 @@ type KeyInfo struct {
 	Privkey  string
 	Metadata *KeyMetadata
 	Relays   RelayMap
+	Nip05    *Nip05Status
+
+	Derivation *Derivation
+	Watch      bool
 }
 
 type KeyMetadata struct {
@@ -35,13 +43,17 @@ type ConnMetadata struct {
 }
 
 type Connection struct {
-	AppID    string        `json:"appid"`
-	Relay    string        `json:"relay"`
-	PubKey   string        `json:"pubkey"`
-	Metadata *ConnMetadata `json:"metadata"`
-	Allows   []string      `json:"allows"`
-	Acked    bool          `json:"acked"`
-	KeyInfo  *KeyInfo      `json:"-"`
+	AppID          string        `json:"appid"`
+	Relay          string        `json:"relay"`
+	PubKey         string        `json:"pubkey"`
+	Metadata       *ConnMetadata `json:"metadata"`
+	Allows         []string      `json:"allows"`
+	Acked          bool          `json:"acked"`
+	PreviewDecrypt bool          `json:"preview_decrypt,omitempty"`
+	DecryptPeers   []string      `json:"decrypt_peers,omitempty"`
+	LastActive     int64         `json:"last_active,omitempty"`
+	PoW            int           `json:"pow,omitempty"`
+	KeyInfo        *KeyInfo      `json:"-"`
 }
 
 type RelayMap map[string]*RelayAttr
@@ -50,9 +62,14 @@ type DB struct {
 	Db *bolt.DB
 }
 
+var (
+	GrantableMethods = []string{"get_public_key", "sign_event", "delegate", "get_relays", "nip04_encrypt", "nip04_decrypt"}
+)
+
 var (
 	errDataNotFound      = errors.New("Data not found")
 	errKeyNotFound       = errors.New("Pubkey not found")
+	errConnNotFound      = errors.New("Connection not found")
 	errInvalidPassphrase = errors.New("Passphrase is wrong")
 )
 
@@ -61,9 +78,23 @@ var (
 	bucketRelays      = []byte("relays")
 	bucketMetadatas   = []byte("metadatas")
 	bucketConnections = []byte("connections")
+	bucketContacts    = []byte("contacts")
+	bucketDelegations = []byte("delegations")
+	bucketQueue       = []byte("queue")
+	bucketRelayStats  = []byte("relaystats")
+	bucketArchive     = []byte("archive")
+	bucketNip05       = []byte("nip05")
+	bucketDerivations = []byte("derivations")
+	bucketSeeds       = []byte("seeds")
+	bucketWatches     = []byte("watches")
+	bucketRelayDrafts = []byte("relaydrafts")
 )
 
 func Open(p string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, err
+	}
+
 	db, err := bolt.Open(p, 0600, nil)
 
 	if err != nil {
@@ -87,6 +118,46 @@ func Open(p string) (*DB, error) {
 			return err
 		}
 
+		if _, err = tx.CreateBucketIfNotExists(bucketContacts); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketDelegations); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketQueue); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketRelayStats); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketArchive); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketNip05); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketDerivations); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketSeeds); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketWatches); err != nil {
+			return err
+		}
+
+		if _, err = tx.CreateBucketIfNotExists(bucketRelayDrafts); err != nil {
+			return err
+		}
+
 		return nil
 	})
 
@@ -97,25 +168,31 @@ func (d *DB) Close() error {
 	return d.Db.Close()
 }
 
+// List returns the stored keys, followed by the watch-only entries.
 func (d *DB) List() (keys []*KeyInfo, err error) {
 	err = d.Db.View(func(t *bolt.Tx) error {
-		c := t.Bucket(bucketKeys).Cursor()
+		for _, bucket := range [][]byte{bucketKeys, bucketWatches} {
+			c := t.Bucket(bucket).Cursor()
 
-		for k, _ := c.First(); k != nil; k, _ = c.Next() {
-			info := new(KeyInfo)
-			info.Pubkey = hex.EncodeToString(k)
+			for k, _ := c.First(); k != nil; k, _ = c.Next() {
+				info := new(KeyInfo)
+				info.Pubkey = hex.EncodeToString(k)
+				info.Watch = bytes.Equal(bucket, bucketWatches)
 
-			buf := t.Bucket(bucketMetadatas).Get(k)
-			if e, err := parseEvent(buf); err == nil {
-				info.Metadata, _ = getUserMeta(e)
-			}
+				buf := t.Bucket(bucketMetadatas).Get(k)
+				if e, err := parseEvent(buf); err == nil {
+					info.Metadata, _ = getUserMeta(e)
+				}
 
-			buf = t.Bucket(bucketRelays).Get(k)
-			if e, err := parseEvent(buf); err == nil {
-				info.Relays, _ = getRelayMap(e)
-			}
+				if e, err := relayEvent(t, k); err == nil {
+					info.Relays, _ = getRelayMap(e)
+				}
 
-			keys = append(keys, info)
+				info.Nip05 = nip05Status(t, k)
+				info.Derivation = derivation(t, k)
+
+				keys = append(keys, info)
+			}
 		}
 
 		return nil
@@ -152,7 +229,15 @@ func (d *DB) GetKey(pub string, pass []byte) (*KeyInfo, error) {
 		priv := tx.Bucket(bucketKeys).Get(pubkey)
 
 		if priv == nil {
-			return errKeyNotFound
+			if tx.Bucket(bucketWatches).Get(pubkey) == nil {
+				return errKeyNotFound
+			}
+
+			if pass != nil {
+				return errWatchOnly
+			}
+
+			result.Watch = true
 		}
 
 		if pass != nil {
@@ -174,23 +259,22 @@ func (d *DB) GetKey(pub string, pass []byte) (*KeyInfo, error) {
 		return nil, err
 	}
 
-	eb, _ := d.getDataById(bucketRelays, pubkey)
+	var re *nostr.Event
 
-	if eb != nil {
-		var e *nostr.Event
-
-		if e, err = parseEvent(eb); err != nil {
-			return nil, err
-		}
+	d.Db.View(func(tx *bolt.Tx) error {
+		re, err = relayEvent(tx, pubkey)
+		return nil
+	})
 
-		if res, err := getRelayMap(e); err == nil {
+	if re != nil {
+		if res, err := getRelayMap(re); err == nil {
 			result.Relays = res
 		} else {
 			return nil, err
 		}
 	}
 
-	eb, _ = d.getDataById(bucketMetadatas, pubkey)
+	eb, _ := d.getDataById(bucketMetadatas, pubkey)
 
 	if eb != nil {
 		var e *nostr.Event
@@ -209,6 +293,24 @@ func (d *DB) GetKey(pub string, pass []byte) (*KeyInfo, error) {
 	return result, nil
 }
 
+func relayEvent(tx *bolt.Tx, key []byte) (*nostr.Event, error) {
+	if e, err := parseEvent(tx.Bucket(bucketRelays).Get(key)); err == nil {
+		return e, nil
+	}
+
+	e, err := parseEvent(tx.Bucket(bucketContacts).Get(key))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(e.Content) == 0 {
+		return nil, errDataNotFound
+	}
+
+	return e, nil
+}
+
 func (d *DB) getDataById(bucket []byte, id []byte) (result []byte, err error) {
 	err = d.Db.View(func(tx *bolt.Tx) error {
 		if result = tx.Bucket(bucket).Get(id); result == nil {
@@ -221,6 +323,22 @@ func (d *DB) getDataById(bucket []byte, id []byte) (result []byte, err error) {
 	return
 }
 
+func (d *DB) getEvent(bucket []byte, pub string) (*nostr.Event, error) {
+	key, err := hex.DecodeString(pub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := d.getDataById(bucket, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEvent(buf)
+}
+
 func (d *DB) SaveKey(pub string, priv []byte) error {
 	key, err := hex.DecodeString(pub)
 
@@ -228,7 +346,14 @@ func (d *DB) SaveKey(pub string, priv []byte) error {
 		return err
 	}
 
-	return d.saveData(bucketKeys, key, priv)
+	return d.Db.Update(func(tx *bolt.Tx) error {
+		// A watched pubkey stops being watch-only with its private key.
+		if err := tx.Bucket(bucketWatches).Delete(key); err != nil {
+			return err
+		}
+
+		return tx.Bucket(bucketKeys).Put(key, priv)
+	})
 }
 
 func (d *DB) SaveRelays(key []byte, relays []string) error {
@@ -253,10 +378,24 @@ func (d *DB) SaveEvent(bucket []byte, event *nostr.Event) error {
 	}
 
 	return d.Db.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket(bucket).Put(key, buf)
+		b := tx.Bucket(bucket)
+
+		if e, err := parseEvent(b.Get(key)); err == nil && e.CreatedAt.After(event.CreatedAt) {
+			return nil
+		}
+
+		return b.Put(key, buf)
 	})
 }
 
+func (d *DB) GetMetadataEvent(pub string) (*nostr.Event, error) {
+	return d.getEvent(bucketMetadatas, pub)
+}
+
+func (d *DB) SaveMetadataEvent(event *nostr.Event) error {
+	return d.SaveEvent(bucketMetadatas, event)
+}
+
 func (d *DB) saveData(bucket []byte, key []byte, data []byte) error {
 	return d.Db.Update(func(tx *bolt.Tx) error {
 		return tx.Bucket(bucket).Put(key, data)
@@ -267,10 +406,20 @@ func (d *DB) Remove(key []byte) (err error) {
 	err = d.Db.Update(func(tx *bolt.Tx) error {
 		tx.Bucket(bucketKeys).Delete(key)
 
+		tx.Bucket(bucketWatches).Delete(key)
+
 		tx.Bucket(bucketMetadatas).Delete(key)
 
 		tx.Bucket(bucketRelays).Delete(key)
 
+		tx.Bucket(bucketRelayDrafts).Delete(key)
+
+		tx.Bucket(bucketContacts).Delete(key)
+
+		tx.Bucket(bucketNip05).Delete(key)
+
+		tx.Bucket(bucketDerivations).Delete(key)
+
 		b := tx.Bucket(bucketConnections)
 		c := b.Cursor()
 		pubkey := hex.EncodeToString(key)
@@ -315,6 +464,28 @@ func (d *DB) ListConnection() (list []*Connection, err error) {
 	return
 }
 
+func (d *DB) GetConnection(id string) (*Connection, error) {
+	key, err := hex.DecodeString(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := d.getDataById(bucketConnections, key)
+
+	if err != nil {
+		return nil, errConnNotFound
+	}
+
+	conn := new(Connection)
+
+	if err = json.Unmarshal(buf, conn); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
 func (d *DB) SetConnection(c *Connection) error {
 	return d.Db.Update(func(tx *bolt.Tx) error {
 		key, err := hex.DecodeString(c.AppID)
@@ -331,6 +502,40 @@ func (d *DB) SetConnection(c *Connection) error {
 	})
 }
 
+// TouchConnection sets when the connection id was last active. Only the
+// timestamp of the stored record changes, a connection removed meanwhile
+// isn't saved again.
+func (d *DB) TouchConnection(id string, active int64) error {
+	key, err := hex.DecodeString(id)
+
+	if err != nil {
+		return err
+	}
+
+	return d.Db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketConnections)
+		buf := b.Get(key)
+
+		if buf == nil {
+			return errConnNotFound
+		}
+
+		var conn map[string]json.RawMessage
+
+		if err := json.Unmarshal(buf, &conn); err != nil {
+			return err
+		}
+
+		conn["last_active"] = json.RawMessage(strconv.FormatInt(active, 10))
+
+		if buf, err = json.Marshal(conn); err != nil {
+			return err
+		}
+
+		return b.Put(key, buf)
+	})
+}
+
 func (d *DB) Disconnect(id string) error {
 	key, err := hex.DecodeString(id)
 
@@ -34,12 +34,47 @@ func getUserMeta(e *nostr.Event) (*KeyMetadata, error) {
 }
 
 func getRelayMap(e *nostr.Event) (RelayMap, error) {
+	if e.Kind == 3 {
+		return getLegacyRelayMap(e)
+	}
+
 	result := make(RelayMap)
-	err := json.Unmarshal([]byte(e.Content), result)
+
+	for _, t := range e.Tags {
+		if len(t) < 2 || t[0] != "r" {
+			continue
+		}
+
+		attr := &RelayAttr{Read: true, Write: true}
+
+		if len(t) > 2 {
+			switch t[2] {
+			case "read":
+				attr.Write = false
+			case "write":
+				attr.Read = false
+			}
+		}
+
+		result[NormalizeRelayURL(t[1])] = attr
+	}
+
+	return result, nil
+}
+
+func getLegacyRelayMap(e *nostr.Event) (RelayMap, error) {
+	m := make(RelayMap)
+	err := json.Unmarshal([]byte(e.Content), &m)
 
 	if err != nil {
 		return nil, err
 	}
 
+	result := make(RelayMap, len(m))
+
+	for u, attr := range m {
+		result[NormalizeRelayURL(u)] = attr
+	}
+
 	return result, nil
-}
\ No newline at end of file
+}
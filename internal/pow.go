@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// MaxPoW is the highest difficulty accepted, it would take ages already.
+const MaxPoW = 64
+
+// DefaultPoWTimeout bounds mining for sign_event requests.
+const DefaultPoWTimeout = time.Minute
+
+var (
+	errInvalidPoW = errors.New("Invalid proof of work difficulty, use 0 to 64")
+)
+
+func CheckPoW(target int) error {
+	if target < 0 || target > MaxPoW {
+		return errInvalidPoW
+	}
+
+	return nil
+}
+
+// Difficulty counts the leading zero bits of an event id as NIP-13 does.
+func Difficulty(id string) int {
+	buf, err := hex.DecodeString(id)
+
+	if err != nil {
+		return 0
+	}
+
+	return zeroBits(buf)
+}
+
+func zeroBits(buf []byte) int {
+	n := 0
+
+	for _, b := range buf {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+
+		n += 8
+	}
+
+	return n
+}
+
+// PoWTarget returns the difficulty committed in the nonce tag of ev, or -1.
+func PoWTarget(ev *nostr.Event) int {
+	t := ev.Tags.GetFirst([]string{"nonce", ""})
+
+	if t == nil || len(*t) < 3 {
+		return -1
+	}
+
+	n, err := strconv.Atoi((*t)[2])
+
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
+
+// MinePoW sets a NIP-13 nonce tag on ev so its id has at least target
+// leading zero bits, trying nonces on workers goroutines until ctx is done.
+// An existing nonce tag committing to target that already meets it is kept.
+func MinePoW(ctx context.Context, ev *nostr.Event, target int, workers int) error {
+	if err := CheckPoW(target); err != nil {
+		return err
+	}
+
+	// The nonce tag of ev may already be good enough.
+	if PoWTarget(ev) >= target && Difficulty(ev.GetID()) >= target {
+		ev.ID = ev.GetID()
+		return nil
+	}
+
+	tags := make(nostr.Tags, 0, len(ev.Tags)+1)
+
+	for _, t := range ev.Tags {
+		if t.Key() != "nonce" {
+			tags = append(tags, t)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	found := make(chan nostr.Tags, 1)
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func(start int) {
+			defer wg.Done()
+
+			e := *ev
+			e.Tags = append(append(make(nostr.Tags, 0, len(tags)+1), tags...), nostr.Tag{"nonce", "", strconv.Itoa(target)})
+			nonce := e.Tags[len(e.Tags)-1]
+
+			for n := start; ctx.Err() == nil; n += workers {
+				nonce[1] = strconv.Itoa(n)
+				sum := sha256.Sum256(e.Serialize())
+
+				if zeroBits(sum[:]) >= target {
+					select {
+					case found <- e.Tags:
+						cancel()
+					default:
+					}
+
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	select {
+	case t := <-found:
+		ev.Tags = t
+		ev.ID = ev.GetID()
+		return nil
+	default:
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,327 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+const (
+	previewContentLimit = 280
+	messagePreviewLimit = 140
+)
+
+var kindNames = map[int]string{
+	0:     "Profile metadata",
+	1:     "Text note",
+	2:     "Recommend relay",
+	3:     "Contact list",
+	4:     "Encrypted direct message",
+	5:     "Deletion",
+	6:     "Repost",
+	7:     "Reaction",
+	13:    "Seal",
+	14:    "Chat message",
+	40:    "Channel creation",
+	41:    "Channel metadata",
+	42:    "Channel message",
+	1984:  "Report",
+	9734:  "Zap request",
+	1059:  "Gift wrap",
+	9735:  "Zap receipt",
+	10002: "Relay list",
+	10050: "DM relay list",
+	22242: "Relay authentication",
+	24133: "Nostr Connect",
+	30023: "Long-form article",
+}
+
+var dangerKinds = map[int]string{
+	0:     "This REPLACES your whole profile",
+	3:     "This REPLACES your whole contact list",
+	5:     "This DELETES events you published",
+	1984:  "This REPORTS a user or note",
+	9734:  "This requests a PAYMENT (zap)",
+	10002: "This REPLACES your relay list",
+	22242: "This LOGS IN to a relay as you",
+}
+
+func DescribeEvent(db *DB, ev *nostr.Event) string {
+	b := new(strings.Builder)
+
+	name, ok := kindNames[ev.Kind]
+	if !ok {
+		name = "Unknown"
+	}
+
+	if reason, ok := dangerKinds[ev.Kind]; ok {
+		fmt.Fprintf(b, "  ⚠️  DANGER: %v!\n\n", reason)
+	}
+
+	fmt.Fprintf(b, "  Kind: %v (%v)\n", ev.Kind, name)
+	fmt.Fprintf(b, "  Created at: %v\n", ev.CreatedAt.Format(time.DateTime))
+
+	if target := PoWTarget(ev); target >= 0 {
+		fmt.Fprintf(b, "  Proof of work: %v bits (target %v)\n", Difficulty(ev.GetID()), target)
+	}
+
+	switch ev.Kind {
+	case 0:
+		describeMetadata(b, db, ev)
+	case 3:
+		describeContacts(b, db, ev)
+	case 10002:
+		describeRelayList(b, ev)
+	case 9734:
+		describeZapRequest(b, ev)
+	case 30023:
+		describeArticle(b, ev)
+	default:
+		describeContent(b, ev.Content)
+		describeTags(b, db, ev.Tags)
+	}
+
+	return b.String()
+}
+
+func DescribeMessage(db *DB, pub string, text string) string {
+	b := new(strings.Builder)
+
+	fmt.Fprintf(b, "  Counterparty: %v\n", describePubkey(db, pub))
+	fmt.Fprintf(b, "\n  Message:\n\n%v\n", indent(truncate(text, messagePreviewLimit), "    "))
+
+	return b.String()
+}
+
+func DescribePubkey(db *DB, pub string) string {
+	return describePubkey(db, pub)
+}
+
+func describeContent(b *strings.Builder, content string) {
+	if len(content) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n  Content:\n\n%v\n", indent(truncate(content, previewContentLimit), "    "))
+}
+
+func describeTags(b *strings.Builder, db *DB, tags nostr.Tags) {
+	if len(tags) == 0 {
+		return
+	}
+
+	fmt.Fprint(b, "\n  Tags:\n")
+
+	for _, t := range tags {
+		fmt.Fprintf(b, "    %v\n", describeTag(db, t))
+	}
+}
+
+func describeTag(db *DB, t nostr.Tag) string {
+	if len(t) < 2 {
+		return strings.Join(t, " ")
+	}
+
+	switch t[0] {
+	case "p":
+		return fmt.Sprintf("mention %v", describePubkey(db, t[1]))
+	case "e":
+		if note, err := nip19.EncodeNote(t[1]); err == nil {
+			return fmt.Sprintf("reference %v", note)
+		}
+	}
+
+	return strings.Join(t, " ")
+}
+
+func describePubkey(db *DB, pub string) string {
+	npub, err := nip19.EncodePublicKey(pub)
+
+	if err != nil {
+		return pub
+	}
+
+	if db != nil {
+		if e, err := db.getEvent(bucketMetadatas, pub); err == nil {
+			if meta, err := getUserMeta(e); err == nil {
+				if name := keyName(&KeyInfo{Metadata: meta}); name != "(no name)" {
+					return fmt.Sprintf("%v (%v)", npub, name)
+				}
+			}
+		}
+	}
+
+	return npub
+}
+
+func describeMetadata(b *strings.Builder, db *DB, ev *nostr.Event) {
+	next := make(map[string]any)
+
+	if err := json.Unmarshal([]byte(ev.Content), &next); err != nil {
+		fmt.Fprintf(b, "\n  Content is not valid JSON:\n\n%v\n", indent(truncate(ev.Content, previewContentLimit), "    "))
+		return
+	}
+
+	prev := make(map[string]any)
+
+	if db != nil {
+		if e, err := db.getEvent(bucketMetadatas, ev.PubKey); err == nil {
+			json.Unmarshal([]byte(e.Content), &prev)
+		}
+	}
+
+	fields := make([]string, 0)
+	for k := range next {
+		fields = append(fields, k)
+	}
+	for k := range prev {
+		if _, ok := next[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+
+	fmt.Fprint(b, "\n  Profile changes:\n\n")
+
+	changed := 0
+	for _, k := range fields {
+		o, hadOld := prev[k]
+		n, hasNew := next[k]
+
+		switch {
+		case !hadOld:
+			fmt.Fprintf(b, "    + %v: %v\n", k, truncate(fmt.Sprint(n), 80))
+		case !hasNew:
+			fmt.Fprintf(b, "    - %v: %v\n", k, truncate(fmt.Sprint(o), 80))
+		case fmt.Sprint(o) != fmt.Sprint(n):
+			fmt.Fprintf(b, "    ~ %v: %v -> %v\n", k, truncate(fmt.Sprint(o), 80), truncate(fmt.Sprint(n), 80))
+		default:
+			continue
+		}
+
+		changed++
+	}
+
+	if changed == 0 {
+		fmt.Fprint(b, "    (no changes)\n")
+	}
+}
+
+func describeContacts(b *strings.Builder, db *DB, ev *nostr.Event) {
+	next := tagValues(ev.Tags, "p")
+	prev := make(map[string]bool)
+	cached := false
+
+	if db != nil {
+		if e, err := db.getEvent(bucketContacts, ev.PubKey); err == nil {
+			prev = tagValues(e.Tags, "p")
+			cached = true
+		}
+	}
+
+	fmt.Fprintf(b, "\n  Following %v accounts", len(next))
+
+	if !cached {
+		fmt.Fprint(b, " (no cached contact list to compare, run 'nkcli update')\n")
+		return
+	}
+
+	fmt.Fprintf(b, ", was %v:\n\n", len(prev))
+
+	added, removed := diffKeys(prev, next), diffKeys(next, prev)
+
+	for _, p := range added {
+		fmt.Fprintf(b, "    + %v\n", describePubkey(db, p))
+	}
+
+	for _, p := range removed {
+		fmt.Fprintf(b, "    - %v\n", describePubkey(db, p))
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		fmt.Fprint(b, "    (no changes)\n")
+	}
+}
+
+func describeRelayList(b *strings.Builder, ev *nostr.Event) {
+	fmt.Fprint(b, "\n  Relays:\n\n")
+
+	for _, t := range ev.Tags {
+		if len(t) < 2 || t[0] != "r" {
+			continue
+		}
+
+		mode := "read/write"
+		if len(t) > 2 {
+			mode = t[2]
+		}
+
+		fmt.Fprintf(b, "    %v (%v)\n", t[1], mode)
+	}
+}
+
+func describeZapRequest(b *strings.Builder, ev *nostr.Event) {
+	if t := ev.Tags.GetFirst([]string{"amount", ""}); t != nil {
+		fmt.Fprintf(b, "  Amount: %v msats\n", t.Value())
+	}
+
+	describeContent(b, ev.Content)
+	describeTags(b, nil, ev.Tags.FilterOut([]string{"relays"}))
+}
+
+func describeArticle(b *strings.Builder, ev *nostr.Event) {
+	if t := ev.Tags.GetFirst([]string{"title", ""}); t != nil {
+		fmt.Fprintf(b, "  Title: %v\n", t.Value())
+	}
+
+	if t := ev.Tags.GetFirst([]string{"summary", ""}); t != nil {
+		fmt.Fprintf(b, "  Summary: %v\n", t.Value())
+	}
+
+	fmt.Fprintf(b, "  Length: %v characters\n", len([]rune(ev.Content)))
+
+	describeContent(b, ev.Content)
+}
+
+func tagValues(tags nostr.Tags, name string) map[string]bool {
+	result := make(map[string]bool)
+
+	for _, t := range tags.GetAll([]string{name, ""}) {
+		result[t.Value()] = true
+	}
+
+	return result
+}
+
+func diffKeys(a, b map[string]bool) []string {
+	result := make([]string, 0)
+
+	for k := range b {
+		if !a[k] {
+			result = append(result, k)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n]) + fmt.Sprintf("... (%v more)", len(r)-n)
+}
+
+func indent(s string, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
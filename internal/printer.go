@@ -3,6 +3,7 @@ package internal
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/nbd-wtf/go-nostr/nip19"
 )
@@ -15,7 +16,17 @@ func PrintKeyList(keys []*KeyInfo) {
 
 func printKey(index int, key *KeyInfo) {
 	npub, _ := nip19.EncodePublicKey(key.Pubkey)
-	fmt.Printf("  %v. %v\n     %v\n     %v\n\n", index+1, keyName(key), npub, key.Pubkey)
+	fmt.Printf("  %v. %v\n     %v\n     %v\n", index+1, keyName(key), npub, key.Pubkey)
+
+	if d := key.Derivation; d != nil {
+		fmt.Printf("     🌱 %v %v\n", d.Fingerprint, d.Path())
+	}
+
+	if key.Watch {
+		fmt.Println("     👀 watch-only, can't sign")
+	}
+
+	fmt.Println()
 }
 
 func keyName(k *KeyInfo) string {
@@ -31,7 +42,7 @@ func keyName(k *KeyInfo) string {
 		}
 
 		if len(k.Metadata.Nip05) > 0 {
-			s = append(s, fmt.Sprintf("<%v>", k.Metadata.Nip05))
+			s = append(s, fmt.Sprintf("<%v>%v", k.Metadata.Nip05, nip05Mark(k)))
 		}
 
 		if len(s) == 0 {
@@ -43,3 +54,81 @@ func keyName(k *KeyInfo) string {
 		return "(no name)"
 	}
 }
+
+func nip05Mark(k *KeyInfo) string {
+	if k.Nip05 == nil || k.Nip05.Identifier != k.Metadata.Nip05 {
+		return ""
+	}
+
+	if k.Nip05.Verified {
+		return " ✅"
+	}
+
+	return " ❌"
+}
+
+func PrintConnectionList(conns []*Connection, keys []*KeyInfo) {
+	for i, c := range conns {
+		fmt.Printf("  %v. %v\n     Key: %v\n     Relay: %v\n     Last active: %v\n\n", i+1, c.Metadata.Name, connKeyName(c, keys), c.Relay, formatActive(c.LastActive))
+	}
+}
+
+func PrintConnection(c *Connection, keys []*KeyInfo) {
+	appid, _ := nip19.EncodePublicKey(c.AppID)
+
+	fmt.Printf("  App name: %v\n", c.Metadata.Name)
+	if len(c.Metadata.Url) != 0 {
+		fmt.Printf("  App URL: %v\n", c.Metadata.Url)
+	}
+	if len(c.Metadata.Description) != 0 {
+		fmt.Printf("  App description: %v\n", c.Metadata.Description)
+	}
+	fmt.Printf("  App ID: %v\n          %v\n", appid, c.AppID)
+	fmt.Printf("  Relay: %v\n", c.Relay)
+	fmt.Printf("  Key: %v\n", connKeyName(c, keys))
+	fmt.Printf("  Acked: %v\n", c.Acked)
+	fmt.Printf("  Last active: %v\n", formatActive(c.LastActive))
+	fmt.Printf("  Preview decrypt: %v\n", c.PreviewDecrypt)
+
+	if c.PoW > 0 {
+		fmt.Printf("  Proof of work: %v bits\n", c.PoW)
+	}
+
+	fmt.Print("\n  Grants:\n")
+	for _, m := range GrantableMethods {
+		mark := "  "
+		if contains(c.Allows, m) {
+			mark = "✅"
+		}
+
+		fmt.Printf("    %v %v\n", mark, m)
+	}
+
+	if len(c.DecryptPeers) > 0 {
+		fmt.Print("\n  Decrypt always allowed from:\n")
+		for _, p := range c.DecryptPeers {
+			npub, _ := nip19.EncodePublicKey(p)
+			fmt.Printf("    %v\n", npub)
+		}
+	}
+}
+
+func connKeyName(c *Connection, keys []*KeyInfo) string {
+	npub, _ := nip19.EncodePublicKey(c.PubKey)
+
+	for _, k := range keys {
+		if k.Pubkey == c.PubKey {
+			return fmt.Sprintf("%v %v", keyName(k), npub)
+		}
+	}
+
+	return fmt.Sprintf("%v (missing)", npub)
+}
+
+func formatActive(ts int64) string {
+	if ts == 0 {
+		return "never"
+	}
+
+	return time.Unix(ts, 0).Format(time.DateTime)
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Prompter reads interactive input. Answers can be preset so every command
+// also runs unattended, when one is missing and stdin isn't a terminal it
+// fails with errNotTerminal instead of blocking.
+type Prompter struct {
+	Passphrase []byte
+	Helper     *CredentialHelper
+	Yes        bool
+	Index      int
+}
+
+// stdin is shared by every prompt so no buffered input gets lost.
+var stdin = bufio.NewReader(os.Stdin)
+
+var (
+	errNotTerminal = errors.New("Input required but stdin is not a terminal, use --key, --index, --yes, --passphrase-file, --passphrase-env or --credential-helper")
+)
+
+func IsTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func (p *Prompter) interactive() error {
+	if !IsTerminal() {
+		return errNotTerminal
+	}
+
+	return nil
+}
+
+// ReadPassphrase returns the preset passphrase, else asks the credential
+// helper, else the terminal. A failing helper is reported and skipped.
+func (p *Prompter) ReadPassphrase(pub string, purpose string, prompt string) ([]byte, error) {
+	if p.Passphrase != nil {
+		return p.Passphrase, nil
+	}
+
+	if p.Helper != nil {
+		pass, err := p.Helper.Get(NewCredentialRequest(pub, purpose))
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		if pass != nil {
+			return pass, nil
+		}
+	}
+
+	return p.ReadSecret(prompt)
+}
+
+// ReadSecret reads a hidden answer from the terminal, it's never preset.
+func (p *Prompter) ReadSecret(prompt string) ([]byte, error) {
+	if err := p.interactive(); err != nil {
+		return nil, err
+	}
+
+	fmt.Print(prompt)
+	secret, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+
+	return secret, err
+}
+
+// Approve lets the credential helper store pass for pub once it worked.
+func (p *Prompter) Approve(pub string, purpose string, pass []byte) {
+	if p.Helper == nil || p.Passphrase != nil {
+		return
+	}
+
+	if err := p.Helper.Store(NewCredentialRequest(pub, purpose), pass); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// Unlock reads the passphrase of pub and decrypts its private key, the
+// credential helper is told whether the passphrase was right.
+func (p *Prompter) Unlock(db *DB, pub string, prompt string) (*KeyInfo, error) {
+	if db.IsWatch(pub) {
+		return nil, errWatchOnly
+	}
+
+	pass, err := p.ReadPassphrase(pub, PurposeUnlock, prompt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := db.GetKey(pub, pass)
+
+	if p.Helper == nil || p.Passphrase != nil {
+		return info, err
+	}
+
+	if errors.Is(err, errInvalidPassphrase) {
+		if err := p.Helper.Erase(NewCredentialRequest(pub, PurposeUnlock)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	} else if err == nil {
+		p.Approve(pub, PurposeUnlock, pass)
+	}
+
+	return info, err
+}
+
+// Confirm asks a y/n question, it's always yes with --yes.
+func (p *Prompter) Confirm(prompt string) (bool, error) {
+	if p.Yes {
+		return true, nil
+	}
+
+	if err := p.interactive(); err != nil {
+		return false, err
+	}
+
+	fmt.Print(prompt)
+
+	return readLine() == "y", nil
+}
+
+// Choose asks for a No. between 1 and n and returns its index, or -1 when
+// the answer is out of range.
+func (p *Prompter) Choose(prompt string, n int) (int, error) {
+	line := strconv.Itoa(p.Index)
+
+	if p.Index == 0 {
+		if err := p.interactive(); err != nil {
+			return -1, err
+		}
+
+		fmt.Print(prompt)
+		line = readLine()
+	}
+
+	i, err := strconv.Atoi(line)
+
+	if err != nil || i < 1 || i > n {
+		return -1, nil
+	}
+
+	return i - 1, nil
+}
+
+func readLine() string {
+	line, _ := stdin.ReadString('\n')
+
+	return strings.TrimSpace(line)
+}
+
+// Ask reads a free form line, it's never preset.
+func (p *Prompter) Ask(prompt string) (string, error) {
+	if err := p.interactive(); err != nil {
+		return "", err
+	}
+
+	fmt.Print(prompt)
+
+	return readLine(), nil
+}
+
+// prompterFrom returns the Prompter stored in ctx, or an empty one.
+func prompterFrom(ctx context.Context) *Prompter {
+	if p, ok := ctx.Value("prompter").(*Prompter); ok {
+		return p
+	}
+
+	return new(Prompter)
+}
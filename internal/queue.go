@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+type QueuedEvent struct {
+	Relay    string       `json:"relay"`
+	Event    *nostr.Event `json:"event"`
+	QueuedAt int64        `json:"queued_at"`
+	Reason   string       `json:"reason,omitempty"`
+}
+
+func (d *DB) QueueEvent(q *QueuedEvent) error {
+	key, err := hex.DecodeString(q.Event.ID)
+
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(q)
+
+	if err != nil {
+		return err
+	}
+
+	return d.saveData(bucketQueue, key, buf)
+}
+
+func (d *DB) ListQueued() (list []*QueuedEvent, err error) {
+	err = d.Db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketQueue).ForEach(func(k, v []byte) error {
+			q := new(QueuedEvent)
+
+			if err := json.Unmarshal(v, q); err != nil {
+				return err
+			}
+
+			list = append(list, q)
+
+			return nil
+		})
+	})
+
+	return
+}
+
+func (d *DB) Dequeue(id string) error {
+	key, err := hex.DecodeString(id)
+
+	if err != nil {
+		return err
+	}
+
+	return d.Db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketQueue).Delete(key)
+	})
+}
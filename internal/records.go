@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"sort"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+// Records are the stable schemas printed by --output json and yaml.
+
+type KeyRecord struct {
+	Pubkey        string         `json:"pubkey"`
+	Npub          string         `json:"npub"`
+	Name          string         `json:"name"`
+	DisplayName   string         `json:"display_name"`
+	Nip05         string         `json:"nip05"`
+	Nip05Verified bool           `json:"nip05_verified"`
+	Relays        []*RelayRecord `json:"relays"`
+	Derivation    *Derivation    `json:"derivation"`
+	Watch         bool           `json:"watch"`
+}
+
+// ImportRecord sums up an import from a file.
+type ImportRecord struct {
+	Imported   []*KeyRecord         `json:"imported"`
+	Skipped    []*ImportIssueRecord `json:"skipped"`
+	Duplicates []*ImportIssueRecord `json:"duplicates"`
+}
+
+type ImportIssueRecord struct {
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+type ShareRecord struct {
+	Index     int    `json:"index"`
+	Threshold int    `json:"threshold"`
+	Shares    int    `json:"shares"`
+	Words     string `json:"words"`
+}
+
+type SeedRecord struct {
+	Fingerprint string   `json:"fingerprint"`
+	Pubkey      string   `json:"pubkey"`
+	Accounts    []uint32 `json:"accounts"`
+	CreatedAt   int64    `json:"created_at"`
+}
+
+type RelayRecord struct {
+	URL   string `json:"url"`
+	Read  bool   `json:"read"`
+	Write bool   `json:"write"`
+}
+
+type ConnectionRecord struct {
+	AppID          string   `json:"app_id"`
+	Name           string   `json:"name"`
+	URL            string   `json:"url"`
+	Description    string   `json:"description"`
+	Relay          string   `json:"relay"`
+	Pubkey         string   `json:"pubkey"`
+	Allows         []string `json:"allows"`
+	Acked          bool     `json:"acked"`
+	PreviewDecrypt bool     `json:"preview_decrypt"`
+	DecryptPeers   []string `json:"decrypt_peers"`
+	LastActive     int64    `json:"last_active"`
+	PoW            int      `json:"pow"`
+}
+
+type PublishRecord struct {
+	Relay   string   `json:"relay"`
+	Status  string   `json:"status"`
+	Notices []string `json:"notices"`
+	Message string   `json:"message,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type PublishedRecord struct {
+	Event   *nostr.Event     `json:"event"`
+	Results []*PublishRecord `json:"results"`
+}
+
+type RelayListRecord struct {
+	Pubkey      string         `json:"pubkey"`
+	Relays      []*RelayRecord `json:"relays"`
+	Unpublished bool           `json:"unpublished"`
+}
+
+type RelayHealthRecord struct {
+	*RelayStats
+	Healthy bool `json:"healthy"`
+}
+
+type VerifyRecord struct {
+	ID     string `json:"id"`
+	Kind   int    `json:"kind"`
+	Pubkey string `json:"pubkey"`
+	Valid  bool   `json:"valid"`
+	Error  string `json:"error,omitempty"`
+}
+
+type MessageRecord struct {
+	ID        string `json:"id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Text      string `json:"text"`
+	CreatedAt int64  `json:"created_at"`
+	Protocol  string `json:"protocol"`
+}
+
+type DisconnectRecord struct {
+	AppID    string `json:"app_id"`
+	Name     string `json:"name"`
+	Notified bool   `json:"notified"`
+	Queued   bool   `json:"queued"`
+	Removed  bool   `json:"removed"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ErrorRecord struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	ExitCode int    `json:"exit_code"`
+}
+
+func (k *KeyInfo) Record() *KeyRecord {
+	npub, _ := nip19.EncodePublicKey(k.Pubkey)
+	r := &KeyRecord{Pubkey: k.Pubkey, Npub: npub, Relays: k.Relays.Records(), Derivation: k.Derivation, Watch: k.Watch}
+
+	if k.Metadata != nil {
+		r.Name = k.Metadata.Username
+		r.DisplayName = k.Metadata.Name
+		r.Nip05 = k.Metadata.Nip05
+		r.Nip05Verified = k.Nip05 != nil && k.Nip05.Verified && k.Nip05.Identifier == r.Nip05
+	}
+
+	return r
+}
+
+func KeyRecords(keys []*KeyInfo) []*KeyRecord {
+	list := make([]*KeyRecord, len(keys))
+
+	for i, k := range keys {
+		list[i] = k.Record()
+	}
+
+	return list
+}
+
+// SeedRecords lists seeds with the accounts stored among keys.
+func SeedRecords(seeds []*Seed, keys []*KeyInfo) []*SeedRecord {
+	list := make([]*SeedRecord, len(seeds))
+
+	for i, s := range seeds {
+		r := &SeedRecord{Fingerprint: s.Fingerprint, Pubkey: s.Pubkey, Accounts: make([]uint32, 0), CreatedAt: s.CreatedAt}
+
+		for _, k := range keys {
+			if k.Derivation != nil && k.Derivation.Fingerprint == s.Fingerprint {
+				r.Accounts = append(r.Accounts, k.Derivation.Account)
+			}
+		}
+
+		sort.Slice(r.Accounts, func(i, j int) bool { return r.Accounts[i] < r.Accounts[j] })
+
+		list[i] = r
+	}
+
+	return list
+}
+
+func (m RelayMap) Records() []*RelayRecord {
+	list := make([]*RelayRecord, 0, len(m))
+
+	for url, attr := range m {
+		list = append(list, &RelayRecord{URL: url, Read: attr.Read, Write: attr.Write})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].URL < list[j].URL
+	})
+
+	return list
+}
+
+func (c *Connection) Record() *ConnectionRecord {
+	r := &ConnectionRecord{
+		AppID:          c.AppID,
+		Relay:          c.Relay,
+		Pubkey:         c.PubKey,
+		Allows:         c.Allows,
+		Acked:          c.Acked,
+		PreviewDecrypt: c.PreviewDecrypt,
+		DecryptPeers:   c.DecryptPeers,
+		LastActive:     c.LastActive,
+		PoW:            c.PoW,
+	}
+
+	if c.Metadata != nil {
+		r.Name = c.Metadata.Name
+		r.URL = c.Metadata.Url
+		r.Description = c.Metadata.Description
+	}
+
+	if r.Allows == nil {
+		r.Allows = []string{}
+	}
+
+	if r.DecryptPeers == nil {
+		r.DecryptPeers = []string{}
+	}
+
+	return r
+}
+
+func ConnectionRecords(conns []*Connection) []*ConnectionRecord {
+	list := make([]*ConnectionRecord, len(conns))
+
+	for i, c := range conns {
+		list[i] = c.Record()
+	}
+
+	return list
+}
+
+func (r *PublishResult) Record() *PublishRecord {
+	rec := &PublishRecord{Relay: r.Relay, Status: r.Status.String(), Notices: r.Notices, Message: r.Message}
+
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+
+	if rec.Notices == nil {
+		rec.Notices = []string{}
+	}
+
+	return rec
+}
+
+func PublishRecords(results []*PublishResult) []*PublishRecord {
+	list := make([]*PublishRecord, len(results))
+
+	for i, r := range results {
+		list[i] = r.Record()
+	}
+
+	return list
+}
+
+func MessageRecords(msgs []*Message) []*MessageRecord {
+	list := make([]*MessageRecord, len(msgs))
+
+	for i, m := range msgs {
+		list[i] = &MessageRecord{
+			ID:        m.ID,
+			From:      m.From,
+			To:        m.To,
+			Text:      m.Text,
+			CreatedAt: m.CreatedAt.Unix(),
+			Protocol:  m.Protocol,
+		}
+	}
+
+	return list
+}
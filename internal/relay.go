@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+type PublishResult struct {
+	Relay   string
+	Status  nostr.Status
+	Notices []string
+	Message string
+	Err     error
+}
+
+func (k *KeyInfo) WriteRelays() []string {
+	result := make([]string, 0)
+
+	for u, v := range k.Relays {
+		if v.Write {
+			result = append(result, u)
+		}
+	}
+
+	return result
+}
+
+func (k *KeyInfo) ReadRelays() []string {
+	result := make([]string, 0)
+
+	for u, v := range k.Relays {
+		if v.Read {
+			result = append(result, u)
+		}
+	}
+
+	return result
+}
+
+func (m RelayMap) Tags() nostr.Tags {
+	urls := make([]string, 0, len(m))
+
+	for u := range m {
+		urls = append(urls, u)
+	}
+
+	sort.Strings(urls)
+
+	tags := make(nostr.Tags, 0, len(urls))
+
+	for _, u := range urls {
+		switch attr := m[u]; {
+		case attr.Read && attr.Write:
+			tags = append(tags, nostr.Tag{"r", u})
+		case attr.Read:
+			tags = append(tags, nostr.Tag{"r", u, "read"})
+		case attr.Write:
+			tags = append(tags, nostr.Tag{"r", u, "write"})
+		}
+	}
+
+	return tags
+}
+
+func (d *DB) GetRelayEvent(pub string) (*nostr.Event, error) {
+	return d.getEvent(bucketRelays, pub)
+}
+
+func (d *DB) SaveRelayEvent(event *nostr.Event) error {
+	return d.SaveEvent(bucketRelays, event)
+}
+
+// SaveRelayDraft keeps the unsigned relay list m of pub apart from the one
+// in use until it's published.
+func (d *DB) SaveRelayDraft(pub string, m RelayMap) (*nostr.Event, error) {
+	event := &nostr.Event{
+		PubKey:    pub,
+		Kind:      10002,
+		CreatedAt: time.Now(),
+		Tags:      m.Tags(),
+		Content:   "",
+	}
+
+	key, err := hex.DecodeString(pub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := json.Marshal(event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return event, d.saveData(bucketRelayDrafts, key, buf)
+}
+
+func (d *DB) GetRelayDraft(pub string) (*nostr.Event, error) {
+	return d.getEvent(bucketRelayDrafts, pub)
+}
+
+func (d *DB) DeleteRelayDraft(pub string) error {
+	key, err := hex.DecodeString(pub)
+
+	if err != nil {
+		return err
+	}
+
+	return d.Db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRelayDrafts).Delete(key)
+	})
+}
+
+// RelayMapOf returns the relays of a kind 10002 or legacy kind 3 event.
+func RelayMapOf(e *nostr.Event) (RelayMap, error) {
+	return getRelayMap(e)
+}
+
+// NormalizeRelayURL is nostr.NormalizeURL with the scheme and host in lower
+// case, so the same relay is one entry of a RelayMap.
+func NormalizeRelayURL(u string) string {
+	u = nostr.NormalizeURL(strings.TrimSpace(u))
+	p, err := url.Parse(u)
+
+	if err != nil {
+		return u
+	}
+
+	p.Scheme = strings.ToLower(p.Scheme)
+	p.Host = strings.ToLower(p.Host)
+
+	return p.String()
+}
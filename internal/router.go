@@ -0,0 +1,473 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/websocket"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+type RelayStats struct {
+	URL         string `json:"url"`
+	Successes   int    `json:"successes"`
+	Failures    int    `json:"failures"`
+	Consecutive int    `json:"consecutive_failures"`
+	LastSuccess int64  `json:"last_success,omitempty"`
+	LastFailure int64  `json:"last_failure,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
+	Latency     int64  `json:"latency_ms"`
+}
+
+type Router struct {
+	db             *DB
+	Boots          []string
+	Overrides      map[string][]string
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	PublishTimeout time.Duration
+}
+
+// defaultPublishTimeout is how long an OK is waited for without a publish
+// timeout, as go-nostr does.
+const defaultPublishTimeout = 3 * time.Second
+
+var (
+	errPublishFailed   = errors.New("Relay rejected the event")
+	errRelayTimeout    = errors.New("Relay timeout")
+	errNoRelayAnswered = errors.New("No relay answered")
+)
+
+const (
+	maxConsecutiveFailures = 3
+	unhealthyBackoff       = time.Hour
+	maxRecipientRelays     = 3
+)
+
+func NewRouter(db *DB, boots []string) *Router {
+	return &Router{db: db, Boots: boots, Timeout: 3 * time.Second}
+}
+
+func (s *RelayStats) Healthy(now time.Time) bool {
+	return s.Consecutive < maxConsecutiveFailures || now.Sub(time.Unix(s.LastFailure, 0)) > unhealthyBackoff
+}
+
+func (r *Router) OutboxRelays(pub string) []string {
+	if list, ok := r.Overrides[pub]; ok {
+		return r.rank(list)
+	}
+
+	if m := r.relayMap(pub); m != nil {
+		if list := (&KeyInfo{Relays: m}).WriteRelays(); len(list) > 0 {
+			return r.rank(list)
+		}
+	}
+
+	return r.rank(r.Boots)
+}
+
+func (r *Router) InboxRelays(pub string) []string {
+	if list, ok := r.Overrides[pub]; ok {
+		return r.rank(list)
+	}
+
+	if m := r.relayMap(pub); m != nil {
+		if list := (&KeyInfo{Relays: m}).ReadRelays(); len(list) > 0 {
+			return r.rank(list)
+		}
+	}
+
+	return nil
+}
+
+func (r *Router) PublishRelays(ctx context.Context, author string, recipients []string) []string {
+	r.discover(ctx, recipients)
+
+	result := r.OutboxRelays(author)
+
+	for _, p := range recipients {
+		inbox := r.InboxRelays(p)
+
+		if len(inbox) > maxRecipientRelays {
+			inbox = inbox[:maxRecipientRelays]
+		}
+
+		result = appendUnique(result, inbox...)
+	}
+
+	return result
+}
+
+func (r *Router) Publish(ctx context.Context, ev *nostr.Event, recipients ...string) []*PublishResult {
+	return r.PublishTo(ctx, r.PublishRelays(ctx, ev.PubKey, recipients), ev)
+}
+
+func (r *Router) PublishTo(ctx context.Context, relays []string, ev *nostr.Event) []*PublishResult {
+	results := make([]*PublishResult, len(relays))
+	wg := new(sync.WaitGroup)
+
+	for i, url := range relays {
+		wg.Add(1)
+
+		go func(i int, url string) {
+			defer wg.Done()
+
+			res := &PublishResult{Relay: url, Status: nostr.PublishStatusFailed}
+			results[i] = res
+			start := time.Now()
+
+			if err := r.publish(ctx, url, ev, res); err != nil {
+				res.Err = err
+				r.record(url, start, err)
+				return
+			}
+
+			if res.Status == nostr.PublishStatusFailed && len(res.Message) > 0 {
+				r.record(url, start, errors.Join(errPublishFailed, errors.New(res.Message)))
+			} else if res.Status == nostr.PublishStatusFailed {
+				r.record(url, start, errPublishFailed)
+			} else {
+				r.record(url, start, nil)
+			}
+		}(i, url)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// publish sends ev to the relay at url and waits for its OK, keeping the
+// message the relay gives with it, like "blocked: ..." or "pow: ...", and
+// the notices it sends meanwhile. The relay may not answer before the
+// publish timeout, the event is then only sent.
+func (r *Router) publish(ctx context.Context, url string, ev *nostr.Event, res *PublishResult) error {
+	dctx := ctx
+
+	if r.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		dctx, cancel = context.WithTimeout(ctx, r.ConnectTimeout)
+		defer cancel()
+	}
+
+	socket, _, err := websocket.DefaultDialer.DialContext(dctx, nostr.NormalizeURL(url), nil)
+
+	if err != nil {
+		return fmt.Errorf("error opening websocket to '%s': %w", url, err)
+	}
+
+	defer socket.Close()
+
+	timeout := r.PublishTimeout
+
+	if timeout <= 0 {
+		timeout = defaultPublishTimeout
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Unblocks the read below when the time is up.
+	go func() {
+		<-pctx.Done()
+		socket.Close()
+	}()
+
+	res.Status = nostr.PublishStatusSent
+	res.Notices = make([]string, 0)
+
+	if err = socket.WriteJSON([]any{"EVENT", ev}); err != nil {
+		return err
+	}
+
+	for {
+		typ, buf, err := socket.ReadMessage()
+
+		if err != nil {
+			return nil
+		}
+
+		var msg []json.RawMessage
+
+		if typ != websocket.TextMessage || json.Unmarshal(buf, &msg) != nil || len(msg) < 2 {
+			continue
+		}
+
+		var label, id string
+		json.Unmarshal(msg[0], &label)
+
+		switch label {
+		case "NOTICE":
+			var notice string
+			json.Unmarshal(msg[1], &notice)
+			res.Notices = append(res.Notices, notice)
+		case "OK":
+			var ok bool
+
+			if json.Unmarshal(msg[1], &id); id != ev.ID || len(msg) < 3 {
+				continue
+			}
+
+			json.Unmarshal(msg[2], &ok)
+
+			if len(msg) > 3 {
+				json.Unmarshal(msg[3], &res.Message)
+			}
+
+			if ok {
+				res.Status = nostr.PublishStatusSucceeded
+			} else {
+				res.Status = nostr.PublishStatusFailed
+			}
+
+			return nil
+		}
+	}
+}
+
+func (r *Router) FetchLatest(ctx context.Context, filter nostr.Filter) *nostr.Event {
+	latest, _ := r.FetchLatestAnswered(ctx, filter)
+
+	return latest
+}
+
+// FetchLatestAnswered is FetchLatest, but tells a missing event from relays
+// that didn't answer: it fails with errNoRelayAnswered when none did.
+func (r *Router) FetchLatestAnswered(ctx context.Context, filter nostr.Filter) (*nostr.Event, error) {
+	var latest *nostr.Event
+
+	relays := make([]string, 0)
+
+	for _, a := range filter.Authors {
+		relays = appendUnique(relays, r.OutboxRelays(a)...)
+	}
+
+	events, answered := r.query(ctx, appendUnique(relays, r.Boots...), nostr.Filters{filter})
+
+	for _, e := range events {
+		if latest == nil || e.CreatedAt.After(latest.CreatedAt) {
+			latest = e
+		}
+	}
+
+	if latest == nil && answered == 0 {
+		return nil, errNoRelayAnswered
+	}
+
+	return latest, nil
+}
+
+func (r *Router) Query(ctx context.Context, relays []string, filters nostr.Filters) []*nostr.Event {
+	events, _ := r.query(ctx, relays, filters)
+
+	return events
+}
+
+// query returns the events of relays and how many of them sent all their
+// stored events.
+func (r *Router) query(ctx context.Context, relays []string, filters nostr.Filters) ([]*nostr.Event, int) {
+	ech := make(chan *nostr.Event)
+	wg := new(sync.WaitGroup)
+	answered := new(atomic.Int32)
+
+	for _, url := range relays {
+		wg.Add(1)
+		go r.subscribe(ctx, url, filters, ech, answered, wg)
+	}
+
+	go func() {
+		wg.Wait()
+		close(ech)
+	}()
+
+	seen := make(map[string]bool)
+	result := make([]*nostr.Event, 0)
+
+	for e := range ech {
+		if e != nil && !seen[e.ID] {
+			seen[e.ID] = true
+			result = append(result, e)
+		}
+	}
+
+	return result, int(answered.Load())
+}
+
+func (r *Router) subscribe(ctx context.Context, relay string, filter nostr.Filters, ch chan<- *nostr.Event, answered *atomic.Int32, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	start := time.Now()
+	conn, err := r.connect(ctx, relay)
+
+	if err != nil {
+		r.record(relay, start, err)
+		return
+	}
+
+	defer conn.Close()
+
+	sub := conn.Subscribe(ctx, filter)
+	defer sub.Unsub()
+
+	for {
+		select {
+		case ev := <-sub.Events:
+			ch <- ev
+		case <-sub.EndOfStoredEvents:
+			answered.Add(1)
+			r.record(relay, start, nil)
+			return
+		case <-time.After(r.Timeout):
+			fmt.Printf("Relay %v connect timeout.\n", relay)
+			r.record(relay, start, errRelayTimeout)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Router) connect(ctx context.Context, url string) (*nostr.Relay, error) {
+	if r.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.ConnectTimeout)
+		defer cancel()
+	}
+
+	return nostr.RelayConnect(ctx, url)
+}
+
+func (r *Router) discover(ctx context.Context, pubs []string) {
+	unknown := make([]string, 0)
+
+	for _, p := range pubs {
+		if r.relayMap(p) == nil {
+			unknown = append(unknown, p)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return
+	}
+
+	for _, e := range r.Query(ctx, r.rank(r.Boots), nostr.Filters{{Kinds: []int{10002}, Authors: unknown}}) {
+		r.db.SaveEvent(bucketRelays, e)
+	}
+}
+
+func (r *Router) relayMap(pub string) RelayMap {
+	e, err := r.db.getEvent(bucketRelays, pub)
+
+	if err != nil {
+		return nil
+	}
+
+	m, err := getRelayMap(e)
+
+	if err != nil || len(m) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+func (r *Router) rank(relays []string) []string {
+	now := time.Now()
+	stats := r.db.RelayStats()
+	healthy, unhealthy := make([]string, 0), make([]string, 0)
+
+	for _, url := range relays {
+		if s, ok := stats[nostr.NormalizeURL(url)]; ok && !s.Healthy(now) {
+			unhealthy = append(unhealthy, url)
+		} else {
+			healthy = append(healthy, url)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return unhealthy
+	}
+
+	sort.SliceStable(healthy, func(i, j int) bool {
+		a, b := stats[nostr.NormalizeURL(healthy[i])], stats[nostr.NormalizeURL(healthy[j])]
+		return a != nil && (b == nil || a.Latency < b.Latency)
+	})
+
+	return healthy
+}
+
+func (r *Router) record(url string, start time.Time, err error) {
+	url = nostr.NormalizeURL(url)
+	now := time.Now()
+
+	r.db.Db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRelayStats)
+		s := &RelayStats{URL: url}
+
+		if buf := b.Get([]byte(url)); buf != nil {
+			json.Unmarshal(buf, s)
+		}
+
+		if err != nil {
+			s.Failures++
+			s.Consecutive++
+			s.LastFailure = now.Unix()
+			s.LastError = err.Error()
+		} else {
+			latency := now.Sub(start).Milliseconds()
+
+			if s.Successes == 0 {
+				s.Latency = latency
+			} else {
+				s.Latency = (s.Latency*3 + latency) / 4
+			}
+
+			s.Successes++
+			s.Consecutive = 0
+			s.LastSuccess = now.Unix()
+		}
+
+		buf, err := json.Marshal(s)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(url), buf)
+	})
+}
+
+func (d *DB) RelayStats() map[string]*RelayStats {
+	result := make(map[string]*RelayStats)
+
+	d.Db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRelayStats).ForEach(func(k, v []byte) error {
+			s := new(RelayStats)
+
+			if json.Unmarshal(v, s) == nil {
+				result[string(k)] = s
+			}
+
+			return nil
+		})
+	})
+
+	return result
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, url := range items {
+		if url = nostr.NormalizeURL(url); !contains(list, url) {
+			list = append(list, url)
+		}
+	}
+
+	return list
+}
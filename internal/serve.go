@@ -12,7 +12,6 @@ import (
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip04"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 type ConnectRequest struct {
@@ -35,9 +34,13 @@ func (cr *ConnectRequest) CheckAllow(name string) error {
 	}
 
 j1:
-	fmt.Printf("\n🔑 Grant access to %v? [y(es)/n(o)/a(lways)]: ", name)
+	answer, err := prompterFrom(cr.ctx).Ask(fmt.Sprintf("\n🔑 Grant access to %v? [y(es)/n(o)/a(lways)]: ", name))
 
-	switch Scanline() {
+	if err != nil {
+		return errors.Join(errUserRejected, err)
+	}
+
+	switch answer {
 	case "y":
 		return nil
 	case "n":
@@ -54,6 +57,37 @@ j1:
 	}
 }
 
+func (cr *ConnectRequest) CheckAllowPeer(name string, peer string) error {
+	if contains(cr.Conn.Allows, name) || contains(cr.Conn.DecryptPeers, peer) {
+		return nil
+	}
+
+j1:
+	answer, err := prompterFrom(cr.ctx).Ask(fmt.Sprintf("\n🔑 Grant access to %v? [y(es)/n(o)/a(lways)/p(eer always)]: ", name))
+
+	if err != nil {
+		return errors.Join(errUserRejected, err)
+	}
+
+	switch answer {
+	case "y":
+		return nil
+	case "n":
+		return errUserRejected
+	case "a":
+		cr.Conn.Allows = append(cr.Conn.Allows, name)
+	case "p":
+		cr.Conn.DecryptPeers = append(cr.Conn.DecryptPeers, peer)
+	default:
+		goto j1
+	}
+
+	db := cr.ctx.Value("db").(*DB)
+	db.SetConnection(cr.Conn)
+
+	return nil
+}
+
 func (cr *ConnectRequest) Response(data any) error {
 	var res map[string]any
 
@@ -115,14 +149,7 @@ func Serve(ctx context.Context, conn *Connection, ch chan<- *ConnectRequest, wg
 	defer sub.Unsub()
 
 	if !conn.Acked {
-		fmt.Print("Enter your passphrase to unlock your private key:")
-		pass, err := terminal.ReadPassword(0)
-
-		if err != nil {
-			return
-		}
-
-		info, err := db.GetKey(conn.PubKey, pass)
+		info, err := prompterFrom(ctx).Unlock(db, conn.PubKey, "Enter your passphrase to unlock your private key:")
 
 		if err != nil {
 			fmt.Printf("\nGet key info fail: %v\n", err)
@@ -180,18 +207,14 @@ j1:
 			return
 		case e := <-sub.Events:
 			if conn.KeyInfo == nil {
-				fmt.Print("Enter your passphrase to unlock your private key:")
-				pass, err := terminal.ReadPassword(0)
+				info, err := prompterFrom(ctx).Unlock(db, conn.PubKey, "Enter your passphrase to unlock your private key:")
 
 				if err != nil {
+					fmt.Printf("\nGet key info fail: %v\n", err)
 					return
 				}
 
-				conn.KeyInfo, err = db.GetKey(conn.PubKey, pass)
-
-				if err != nil {
-					return
-				}
+				conn.KeyInfo = info
 			}
 
 			shared, err := nip04.ComputeSharedSecret(conn.AppID, conn.KeyInfo.Privkey)
@@ -0,0 +1,283 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+)
+
+// Kinds of secrets a Shamir backup can hold.
+const (
+	ShareKindKey     byte = 0
+	ShareKindEntropy byte = 1
+)
+
+const (
+	shareVersion   = 1
+	shareHeaderLen = 8
+	shareSumLen    = 4
+	MaxShares      = 16
+)
+
+// ShamirShare is one share of a secret split over GF(256). It's written as
+// BIP-39 English words: version, kind, language, identifier, threshold,
+// index and value length, then the value and a checksum, 11 bits a word.
+type ShamirShare struct {
+	Kind      byte
+	Language  string
+	ID        uint16
+	Threshold int
+	Index     int
+	Value     []byte
+}
+
+var (
+	errInvalidThreshold = fmt.Errorf("Invalid threshold, need 1 <= threshold <= shares <= %v", MaxShares)
+	errInvalidShare     = errors.New("Invalid share")
+	errShareChecksum    = errors.New("Share checksum mismatch, check the words")
+	errShareMismatch    = errors.New("Shares are from different backups")
+	errDuplicateShare   = errors.New("Same share given twice")
+	errNotEnoughShares  = errors.New("Not enough shares")
+)
+
+var gfExp, gfLog [256]byte
+
+func init() {
+	x := byte(1)
+
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = byte(i)
+
+		// Multiply by the generator 3 modulo x^8 + x^4 + x^3 + x + 1.
+		hi := x & 0x80
+		x2 := x << 1
+
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+
+		x ^= x2
+	}
+
+	gfExp[255] = gfExp[0]
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return gfExp[(int(gfLog[a])+int(gfLog[b]))%255]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+
+	return gfExp[(int(gfLog[a])-int(gfLog[b])+255)%255]
+}
+
+func CheckShamirParams(threshold int, n int) error {
+	if threshold < 1 || threshold > n || n > MaxShares {
+		return errInvalidThreshold
+	}
+
+	return nil
+}
+
+// ShamirSplit splits secret into n shares, any threshold of them recover it.
+func ShamirSplit(kind byte, language string, secret []byte, threshold int, n int) ([]*ShamirShare, error) {
+	if err := CheckShamirParams(threshold, n); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 2)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	shares := make([]*ShamirShare, n)
+
+	for i := range shares {
+		shares[i] = &ShamirShare{
+			Kind:      kind,
+			Language:  language,
+			ID:        binary.BigEndian.Uint16(id),
+			Threshold: threshold,
+			Index:     i + 1,
+			Value:     make([]byte, len(secret)),
+		}
+	}
+
+	coeffs := make([]byte, threshold)
+
+	for b, s := range secret {
+		coeffs[0] = s
+
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+
+		for _, share := range shares {
+			x, y := byte(share.Index), byte(0)
+
+			// Horner's method from the highest coefficient.
+			for j := threshold - 1; j >= 0; j-- {
+				y = gfMul(y, x) ^ coeffs[j]
+			}
+
+			share.Value[b] = y
+		}
+	}
+
+	return shares, nil
+}
+
+// ShamirCombine recovers the secret from threshold shares of one backup.
+func ShamirCombine(shares []*ShamirShare) ([]byte, error) {
+	if len(shares) == 0 || len(shares) < shares[0].Threshold {
+		return nil, errNotEnoughShares
+	}
+
+	first := shares[0]
+	shares = shares[:first.Threshold]
+	seen := make(map[int]bool)
+
+	for _, s := range shares {
+		if s.ID != first.ID || s.Kind != first.Kind || s.Threshold != first.Threshold || len(s.Value) != len(first.Value) {
+			return nil, errShareMismatch
+		}
+
+		if seen[s.Index] {
+			return nil, errDuplicateShare
+		}
+
+		seen[s.Index] = true
+	}
+
+	secret := make([]byte, len(first.Value))
+
+	for i, si := range shares {
+		// Lagrange basis polynomial of share i at x = 0.
+		basis := byte(1)
+
+		for j, sj := range shares {
+			if i != j {
+				basis = gfMul(basis, gfDiv(byte(sj.Index), byte(sj.Index)^byte(si.Index)))
+			}
+		}
+
+		for b := range secret {
+			secret[b] ^= gfMul(si.Value[b], basis)
+		}
+	}
+
+	return secret, nil
+}
+
+func (s *ShamirShare) bytes() []byte {
+	buf := []byte{shareVersion, s.Kind, byte(languageID(s.Language)), 0, 0, byte(s.Threshold), byte(s.Index), byte(len(s.Value))}
+	binary.BigEndian.PutUint16(buf[3:5], s.ID)
+	buf = append(buf, s.Value...)
+	sum := sha256.Sum256(buf)
+
+	return append(buf, sum[:shareSumLen]...)
+}
+
+// Words encodes s as BIP-39 English words.
+func (s *ShamirShare) Words() string {
+	buf := s.bytes()
+	count := (len(buf)*8 + 10) / 11
+	words := make([]string, count)
+
+	for i := range words {
+		idx := 0
+
+		for bit := i * 11; bit < i*11+11; bit++ {
+			idx <<= 1
+
+			if bit/8 < len(buf) && buf[bit/8]&(0x80>>(bit%8)) != 0 {
+				idx |= 1
+			}
+		}
+
+		words[i] = wordlists.English[idx]
+	}
+
+	return strings.Join(words, " ")
+}
+
+// ParseShamirShare decodes the words of a share and checks its checksum.
+func ParseShamirShare(words string) (*ShamirShare, error) {
+	index := make(map[string]int, len(wordlists.English))
+
+	for i, w := range wordlists.English {
+		index[w] = i
+	}
+
+	list := strings.Fields(strings.ToLower(words))
+	buf := make([]byte, (len(list)*11)/8)
+
+	for i, w := range list {
+		idx, ok := index[w]
+
+		if !ok {
+			return nil, errors.Join(errInvalidShare, fmt.Errorf("unknown word %v", w))
+		}
+
+		for bit := 0; bit < 11; bit++ {
+			if idx&(1<<(10-bit)) != 0 {
+				pos := i*11 + bit
+
+				if pos/8 >= len(buf) {
+					return nil, errInvalidShare
+				}
+
+				buf[pos/8] |= 0x80 >> (pos % 8)
+			}
+		}
+	}
+
+	if len(buf) < shareHeaderLen || buf[0] != shareVersion {
+		return nil, errInvalidShare
+	}
+
+	size := shareHeaderLen + int(buf[7]) + shareSumLen
+
+	if len(buf) < size || bytes.Count(buf[size:], []byte{0}) != len(buf)-size {
+		return nil, errInvalidShare
+	}
+
+	sum := sha256.Sum256(buf[:size-shareSumLen])
+
+	if !bytes.Equal(sum[:shareSumLen], buf[size-shareSumLen:size]) {
+		return nil, errShareChecksum
+	}
+
+	s := &ShamirShare{
+		Kind:      buf[1],
+		ID:        binary.BigEndian.Uint16(buf[3:5]),
+		Threshold: int(buf[5]),
+		Index:     int(buf[6]),
+		Value:     buf[shareHeaderLen : size-shareSumLen],
+	}
+
+	if int(buf[2]) < len(languageIDs) {
+		s.Language = languageIDs[buf[2]]
+	}
+
+	if s.Index < 1 || s.Threshold < 1 || (s.Kind != ShareKindKey && s.Kind != ShareKindEntropy) {
+		return nil, errInvalidShare
+	}
+
+	return s, nil
+}
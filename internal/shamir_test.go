@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+)
+
+var shamirSecret = bytes.Repeat([]byte{0x00, 0x5a, 0xff, 0x13}, 8)
+
+func splitSecret(t *testing.T, threshold int, n int) []*ShamirShare {
+	shares, err := ShamirSplit(ShareKindKey, "english", shamirSecret, threshold, n)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return shares
+}
+
+func TestShamirCombineSubsets(t *testing.T) {
+	shares := splitSecret(t, 3, 5)
+
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				// Order doesn't matter.
+				secret, err := ShamirCombine([]*ShamirShare{shares[c], shares[a], shares[b]})
+
+				if err != nil {
+					t.Fatalf("shares %v %v %v: %v", a+1, b+1, c+1, err)
+				}
+
+				if !bytes.Equal(secret, shamirSecret) {
+					t.Errorf("shares %v %v %v: got %x", a+1, b+1, c+1, secret)
+				}
+			}
+		}
+	}
+}
+
+func TestShamirCombineBelowThreshold(t *testing.T) {
+	shares := splitSecret(t, 3, 5)
+
+	if _, err := ShamirCombine(shares[:2]); !errors.Is(err, errNotEnoughShares) {
+		t.Errorf("2 of 3 shares: %v", err)
+	}
+
+	if _, err := ShamirCombine(nil); !errors.Is(err, errNotEnoughShares) {
+		t.Errorf("no shares: %v", err)
+	}
+
+	// Shares lying about their threshold don't give the secret away.
+	for _, s := range shares[:2] {
+		s.Threshold = 2
+	}
+
+	secret, err := ShamirCombine(shares[:2])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(secret, shamirSecret) {
+		t.Error("2 of 3 shares recovered the secret")
+	}
+}
+
+func TestShamirCombineRejects(t *testing.T) {
+	shares := splitSecret(t, 2, 3)
+	other := splitSecret(t, 2, 3)
+
+	if _, err := ShamirCombine([]*ShamirShare{shares[0], shares[0]}); !errors.Is(err, errDuplicateShare) {
+		t.Errorf("duplicate share: %v", err)
+	}
+
+	// Backups are told apart by a random identifier, make sure they differ.
+	other[1].ID = shares[0].ID + 1
+
+	if _, err := ShamirCombine([]*ShamirShare{shares[0], other[1]}); !errors.Is(err, errShareMismatch) {
+		t.Errorf("shares of another backup: %v", err)
+	}
+
+	entropy := *shares[1]
+	entropy.Kind = ShareKindEntropy
+
+	if _, err := ShamirCombine([]*ShamirShare{shares[0], &entropy}); !errors.Is(err, errShareMismatch) {
+		t.Errorf("shares of another kind: %v", err)
+	}
+}
+
+func TestShamirSplitParams(t *testing.T) {
+	for _, p := range [][2]int{{0, 3}, {4, 3}, {2, MaxShares + 1}} {
+		if _, err := ShamirSplit(ShareKindKey, "english", shamirSecret, p[0], p[1]); !errors.Is(err, errInvalidThreshold) {
+			t.Errorf("%v of %v: %v", p[0], p[1], err)
+		}
+	}
+}
+
+func TestShamirShareWords(t *testing.T) {
+	for _, s := range splitSecret(t, 3, 5) {
+		parsed, err := ParseShamirShare(s.Words())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if parsed.Kind != s.Kind || parsed.Language != s.Language || parsed.ID != s.ID ||
+			parsed.Threshold != s.Threshold || parsed.Index != s.Index || !bytes.Equal(parsed.Value, s.Value) {
+			t.Errorf("got %+v, want %+v", parsed, s)
+		}
+	}
+}
+
+func TestShamirShareChecksum(t *testing.T) {
+	s := splitSecret(t, 2, 3)[0]
+	words := strings.Fields(s.Words())
+
+	// Change a word of the value, past the header.
+	i := len(words) / 2
+	pos := 0
+
+	for j, w := range wordlists.English {
+		if w == words[i] {
+			pos = j
+		}
+	}
+
+	words[i] = wordlists.English[pos^1]
+
+	if _, err := ParseShamirShare(strings.Join(words, " ")); !errors.Is(err, errShareChecksum) {
+		t.Errorf("changed word: %v", err)
+	}
+
+	if _, err := ParseShamirShare(s.Words() + " notaword"); !errors.Is(err, errInvalidShare) {
+		t.Errorf("unknown word: %v", err)
+	}
+
+	if _, err := ParseShamirShare(strings.Join(strings.Fields(s.Words())[:5], " ")); !errors.Is(err, errInvalidShare) {
+		t.Errorf("truncated share: %v", err)
+	}
+}
@@ -35,6 +35,15 @@ var (
 	errInvalidEventField = errors.New("Invalid event field")
 )
 
+// generatePrivateKey is swapped in tests.
+var generatePrivateKey = nostr.GeneratePrivateKey
+
+// newPrivateKey generates a random private key. go-nostr drops its leading
+// zeros, it's padded back to 64 hex characters.
+func newPrivateKey() string {
+	return fmt.Sprintf("%064s", generatePrivateKey())
+}
+
 func SerializeKeys(l []string) []string {
 	result := make([]string, 0)
 
@@ -48,7 +57,7 @@ func SerializeKeys(l []string) []string {
 
 			result = append(result, res.(string))
 		} else {
-			if regexp.MustCompile(`^[a-f0-9]{64}$`).MatchString(it) {
+			if !hexKeyRegexp.MatchString(it) {
 				continue
 			}
 
@@ -136,14 +145,41 @@ func ParseEvent(obj map[string]any) (*nostr.Event, error) {
 		e.Content = v
 	}
 
-	e.Tags = parseTags(obj["tags"].([]interface{}))
+	if v, ok := obj["tags"].([]interface{}); ok {
+		tags, err := parseTags(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		e.Tags = tags
+	}
 
 	return e, nil
 }
 
-func parseTags(i []interface{}) (tags nostr.Tags) {
+func parseTags(i []interface{}) (tags nostr.Tags, err error) {
 	for _, item := range i {
-		tags = append(tags, nostr.Tag(item.([]string)))
+		fields, ok := item.([]interface{})
+
+		if !ok {
+			return nil, errInvalidEventField
+		}
+
+		tag := make(nostr.Tag, 0, len(fields))
+
+		for _, f := range fields {
+			s, ok := f.(string)
+
+			if !ok {
+				return nil, errInvalidEventField
+			}
+
+			tag = append(tag, s)
+		}
+
+		tags = append(tags, tag)
 	}
+
 	return
 }
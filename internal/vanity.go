@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const Bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// VanityPattern matches the leading bits of a pubkey. A bech32 character of
+// an npub is 5 bits of the key and a hex character 4, so the chance of a
+// random key to match is exactly 2^-Bits.
+type VanityPattern struct {
+	Prefix string
+	Hex    bool
+	Bits   int
+	value  []byte
+	mask   []byte
+}
+
+var (
+	errInvalidVanity = errors.New("Invalid vanity prefix")
+	errVanityTooLong = errors.New("Vanity prefix is too long")
+)
+
+// NewVanityPattern parses a bech32 prefix, with or without npub1, or a hex
+// prefix when isHex.
+func NewVanityPattern(prefix string, isHex bool) (*VanityPattern, error) {
+	p := &VanityPattern{Prefix: strings.ToLower(prefix), Hex: isHex}
+	charset, width := Bech32Charset, 5
+
+	if isHex {
+		charset, width = "0123456789abcdef", 4
+	} else {
+		p.Prefix = strings.TrimPrefix(p.Prefix, "npub1")
+	}
+
+	if len(p.Prefix) == 0 {
+		return nil, errInvalidVanity
+	}
+
+	p.Bits = len(p.Prefix) * width
+
+	// The 52nd npub character holds a single bit of the key.
+	if p.Bits > 255 {
+		return nil, errors.Join(errVanityTooLong, errors.New(prefix))
+	}
+
+	p.value = make([]byte, (p.Bits+7)/8)
+	p.mask = make([]byte, len(p.value))
+
+	for i, ch := range p.Prefix {
+		v := strings.IndexRune(charset, ch)
+
+		if v < 0 {
+			return nil, errors.Join(errInvalidVanity, invalidCharsError(p.Prefix, charset))
+		}
+
+		for b := 0; b < width; b++ {
+			pos := i*width + b
+			p.mask[pos/8] |= 0x80 >> (pos % 8)
+
+			if v&(1<<(width-1-b)) != 0 {
+				p.value[pos/8] |= 0x80 >> (pos % 8)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func invalidCharsError(prefix string, charset string) error {
+	bad := make([]string, 0)
+
+	for _, ch := range prefix {
+		if !strings.ContainsRune(charset, ch) && !contains(bad, string(ch)) {
+			bad = append(bad, string(ch))
+		}
+	}
+
+	return errors.New("not allowed: " + strings.Join(bad, ", ") + ", use " + charset)
+}
+
+// Difficulty is the expected number of keys to try.
+func (p *VanityPattern) Difficulty() float64 {
+	return math.Pow(2, float64(p.Bits))
+}
+
+// Chance is the probability to have found a key after tried keys.
+func (p *VanityPattern) Chance(tried uint64) float64 {
+	return -math.Expm1(float64(tried) * math.Log1p(-1/p.Difficulty()))
+}
+
+func (p *VanityPattern) Match(pub []byte) bool {
+	for i, m := range p.mask {
+		if pub[i]&m != p.value[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SearchVanity tries random keys on workers goroutines until one matches p
+// or ctx is done. progress is called every second with the keys tried.
+func SearchVanity(ctx context.Context, p *VanityPattern, workers int, progress func(tried uint64, elapsed time.Duration)) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var tried atomic.Uint64
+	found := make(chan string, 1)
+	wg := new(sync.WaitGroup)
+	start := time.Now()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				priv := newPrivateKey()
+				pub, err := nostr.GetPublicKey(priv)
+				tried.Add(1)
+
+				if err != nil {
+					continue
+				}
+
+				if buf, err := hex.DecodeString(pub); err == nil && p.Match(buf) {
+					select {
+					case found <- priv:
+						cancel()
+					default:
+					}
+
+					return
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case priv := <-found:
+			wg.Wait()
+			return priv, nil
+		case <-ctx.Done():
+			wg.Wait()
+
+			select {
+			case priv := <-found:
+				return priv, nil
+			default:
+				return "", ctx.Err()
+			}
+		case <-ticker.C:
+			if progress != nil {
+				progress(tried.Load(), time.Since(start))
+			}
+		}
+	}
+}
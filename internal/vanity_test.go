@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestSearchVanityPadsShortKeys(t *testing.T) {
+	short := strings.Repeat("ab", 31)
+	priv := "00" + short
+	pub, err := nostr.GetPublicKey(priv)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(f func() string) { generatePrivateKey = f }(generatePrivateKey)
+	generatePrivateKey = func() string { return short }
+
+	p, err := NewVanityPattern(pub[:4], true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := SearchVanity(context.Background(), p, 1, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found != priv {
+		t.Errorf("found %v, want %v", found, priv)
+	}
+
+	if !hexKeyRegexp.MatchString(found) {
+		t.Errorf("%v isn't a 64 hex characters key", found)
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var (
+	errWatchOnly = errors.New("Key is watch-only, it has no private key to sign")
+)
+
+// SaveWatch adds pub as a watch-only entry, it has metadata and relays like
+// a key but no private key.
+func (d *DB) SaveWatch(pub string) error {
+	key, err := hex.DecodeString(pub)
+
+	if err != nil {
+		return err
+	}
+
+	return d.saveData(bucketWatches, key, []byte(strconv.FormatInt(time.Now().Unix(), 10)))
+}
+
+func (d *DB) IsWatch(pub string) bool {
+	key, err := hex.DecodeString(pub)
+
+	if err != nil {
+		return false
+	}
+
+	return d.Db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketWatches).Get(key) == nil {
+			return errDataNotFound
+		}
+
+		return nil
+	}) == nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	nkcli "github.com/mdzz-club/nkcli/internal"
 	"github.com/urfave/cli/v2"
@@ -24,14 +25,30 @@ func listAction(c *cli.Context) error {
 		return err
 	}
 
-	if len(list) == 0 {
-		fmt.Print("You don't have any keys, generate one or import.\n")
-		return nil
+	if fp := c.String("family"); len(fp) > 0 {
+		family := make([]*nkcli.KeyInfo, 0)
+
+		for _, k := range list {
+			if k.Derivation != nil && strings.EqualFold(k.Derivation.Fingerprint, fp) {
+				family = append(family, k)
+			}
+		}
+
+		list = family
+	}
+
+	if !c.Bool("no-verify") {
+		db.VerifyKeys(c.Context, nip05Verifier, list, false)
 	}
 
-	fmt.Printf("You have %v keys:\n\n", len(list))
+	return render(nkcli.KeyRecords(list), func() {
+		if len(list) == 0 {
+			fmt.Print("You don't have any keys, generate one or import.\n")
+			return
+		}
 
-	nkcli.PrintKeyList(list)
+		fmt.Printf("You have %v keys:\n\n", len(list))
 
-	return nil
+		nkcli.PrintKeyList(list)
+	})
 }
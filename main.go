@@ -3,7 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 
+	nkcli "github.com/mdzz-club/nkcli/internal"
 	"github.com/urfave/cli/v2"
 )
 
@@ -11,6 +15,11 @@ var (
 	version = "1.1.0"
 )
 
+var (
+	config   = new(nkcli.Config)
+	prompter = new(nkcli.Prompter)
+)
+
 var (
 	DefaultRelays = []string{
 		"wss://relay.damus.io",
@@ -28,6 +37,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	confpath, err := nkcli.ConfigPath()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
 	app := &cli.App{
 		Name:  "nkcli",
 		Usage: "Manage Nostr keys",
@@ -39,7 +55,52 @@ func main() {
 				Value:   dbpath,
 				EnvVars: []string{"NKCLI_DB"},
 			},
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "Config file",
+				Value:   confpath,
+				EnvVars: []string{"NKCLI_CONFIG"},
+			},
+			&cli.BoolFlag{
+				Name:    "archive",
+				Usage:   "Keep a local archive of every event nkcli signed",
+				EnvVars: []string{"NKCLI_ARCHIVE"},
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Usage:   "Output format: table, json or yaml",
+				Value:   outputTable,
+				EnvVars: []string{"NKCLI_OUTPUT"},
+			},
+			&cli.StringFlag{
+				Name:  "passphrase-file",
+				Usage: "Read key passphrase from file instead of prompting",
+			},
+			&cli.StringFlag{
+				Name:  "passphrase-env",
+				Usage: "Read key passphrase from this environment variable instead of prompting",
+			},
+			&cli.StringFlag{
+				Name:    "credential-helper",
+				Usage:   "Ask this program for key passphrases, see README",
+				EnvVars: []string{"NKCLI_CREDENTIAL_HELPER"},
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Answer yes to confirmations, connection grants are still asked",
+			},
+			&cli.IntFlag{
+				Name:  "index",
+				Usage: "Answer No. to key or connection choices",
+			},
+			&cli.DurationFlag{
+				Name:  "max-delegation-lifetime",
+				Usage: "Reject delegation requests valid for longer than this, 0 to disable",
+				Value: nkcli.DefaultMaxDelegationLifetime,
+			},
 		},
+		Before:  loadConfig,
 		Action:  serveAction,
 		Version: version,
 		Commands: []*cli.Command{
@@ -47,13 +108,68 @@ func main() {
 				Name:    "generate",
 				Aliases: []string{"g"},
 				Usage:   "Generate a new key",
-				Action:  generateAction,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "words",
+						Usage: "Number of mnemonic words: 12, 15, 18, 21 or 24",
+						Value: 12,
+					},
+					&cli.StringFlag{
+						Name:  "language",
+						Usage: "Mnemonic wordlist: " + strings.Join(nkcli.LanguageNames(), ", "),
+						Value: nkcli.DefaultLanguage,
+					},
+					&cli.BoolFlag{
+						Name:  "mnemonic-passphrase",
+						Usage: "Derive with a BIP-39 passphrase, read from $NKCLI_MNEMONIC_PASSPHRASE or asked",
+					},
+					&cli.UintFlag{
+						Name:  "account",
+						Usage: "NIP-06 account, derives m/44'/1237'/<account>'/0/0",
+					},
+					&cli.BoolFlag{
+						Name:  "no-check",
+						Usage: "Don't ask for mnemonic words to check they are written down",
+					},
+					&cli.BoolFlag{
+						Name:  "store-mnemonic",
+						Usage: "Keep the mnemonic encrypted with the key passphrase to derive more accounts later",
+					},
+					&cli.StringFlag{
+						Name:  "vanity-prefix",
+						Usage: "Search a random key whose npub starts with this, e.g. npub1abc",
+					},
+					&cli.StringFlag{
+						Name:  "hex-prefix",
+						Usage: "Search a random key whose hex pubkey starts with this",
+					},
+					&cli.IntFlag{
+						Name:  "threads",
+						Usage: "Threads for the vanity search",
+						Value: runtime.NumCPU(),
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "Give up the vanity search after this, 0 to search until found",
+					},
+				},
+				Action: generateAction,
 			},
 			{
 				Name:    "list",
 				Aliases: []string{"l"},
 				Usage:   "List keys",
-				Action:  listAction,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "no-verify",
+						Usage: "Don't refresh stale NIP-05 verifications",
+					},
+					&cli.StringFlag{
+						Name:  "family",
+						Usage: "Only list keys derived from the seed with this fingerprint",
+					},
+				},
+				Action: listAction,
 			},
 			{
 				Name:    "update",
@@ -64,6 +180,7 @@ func main() {
 						Name:    "relay",
 						Aliases: []string{"r"},
 						Usage:   "Use specific relay",
+						EnvVars: []string{"NKCLI_RELAYS"},
 					},
 				},
 				Action: updateAction,
@@ -77,12 +194,42 @@ func main() {
 						Name:    "relay",
 						Aliases: []string{"r"},
 						Usage:   "Use specific relay to retrieve metadata",
+						EnvVars: []string{"NKCLI_RELAYS"},
 					},
 					&cli.BoolFlag{
 						Name:  "raw",
 						Usage: "Use raw nsec1 or hex encoded private key",
 						Value: false,
 					},
+					&cli.StringFlag{
+						Name:  "language",
+						Usage: "Mnemonic wordlist, detected if omitted: " + strings.Join(nkcli.LanguageNames(), ", "),
+					},
+					&cli.BoolFlag{
+						Name:  "mnemonic-passphrase",
+						Usage: "Derive with a BIP-39 passphrase, read from $NKCLI_MNEMONIC_PASSPHRASE or asked",
+					},
+					&cli.StringFlag{
+						Name:  "account",
+						Usage: "NIP-06 account N or range N-M to import, up to 1000 accounts",
+						Value: "0",
+					},
+					&cli.BoolFlag{
+						Name:  "store-mnemonic",
+						Usage: "Keep the mnemonic encrypted with the key passphrase to derive more accounts later",
+					},
+					&cli.BoolFlag{
+						Name:  "shamir",
+						Usage: "Recover from Shamir shares, asked or one a line from stdin",
+					},
+					&cli.BoolFlag{
+						Name:  "watch",
+						Usage: "Add npub1 or hex pubkeys as watch-only entries, without private key",
+					},
+					&cli.StringFlag{
+						Name:  "from",
+						Usage: "Import every nsec, hex key, ncryptsec or mnemonic of file, one a line, or a signer JSON export, - for stdin",
+					},
 				},
 				Action: importAction,
 			},
@@ -96,35 +243,711 @@ func main() {
 				Aliases: []string{"c"},
 				Usage:   "Create new connection via nostrconnect://",
 				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "key",
+						Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+					},
 					&cli.BoolFlag{
 						Name:    "allow-all",
 						Aliases: []string{"A"},
 						Usage:   "Allow all request always",
 						Value:   false,
 					},
+					&cli.BoolFlag{
+						Name:  "preview-decrypt",
+						Usage: "Decrypt and preview messages before granting nip04_decrypt",
+						Value: false,
+					},
+					&cli.IntFlag{
+						Name:  "pow",
+						Usage: "Mine a NIP-13 proof of work of this many bits for sign_event requests",
+					},
 				},
 				ArgsUsage: "nostrconnect://...",
 				Action:    connectAction,
 			},
 			{
-				Name:   "disconnect",
-				Usage:  "Disconnect and remove connection",
+				Name:  "disconnect",
+				Usage: "Disconnect and remove connection",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "offline",
+						Usage: "Remove connection without notifying the app",
+					},
+					&cli.BoolFlag{
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "Remove connection even if notifying the app failed",
+					},
+					&cli.StringSliceFlag{
+						Name:  "app",
+						Usage: "Disconnect all connections with this app name",
+					},
+					&cli.StringSliceFlag{
+						Name:  "id",
+						Usage: "Disconnect connection with this App ID",
+					},
+					&cli.StringSliceFlag{
+						Name:  "key",
+						Usage: "Disconnect all connections bound to this key",
+					},
+				},
 				Action: disconnectAction,
 			},
+			{
+				Name:    "connections",
+				Aliases: []string{"conn"},
+				Usage:   "Inspect and edit connections",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"l"},
+						Usage:   "List connections",
+						Action:  connectionsListAction,
+					},
+					{
+						Name:      "show",
+						Usage:     "Show connection detail",
+						ArgsUsage: "[No.|App ID|name]",
+						Action:    connectionsShowAction,
+					},
+					{
+						Name:      "edit",
+						Usage:     "Edit connection grants, relay or bound key",
+						ArgsUsage: "[No.|App ID|name]",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "allow",
+								Usage: "Always allow method, can be repeated",
+							},
+							&cli.StringSliceFlag{
+								Name:  "deny",
+								Usage: "Ask again before method, can be repeated",
+							},
+							&cli.BoolFlag{
+								Name:  "allow-all",
+								Usage: "Always allow all methods",
+							},
+							&cli.BoolFlag{
+								Name:  "deny-all",
+								Usage: "Revoke all grants",
+							},
+							&cli.StringSliceFlag{
+								Name:  "allow-peer",
+								Usage: "Always allow decrypting messages from pubkey",
+							},
+							&cli.StringSliceFlag{
+								Name:  "deny-peer",
+								Usage: "Revoke decrypt grant for pubkey",
+							},
+							&cli.BoolFlag{
+								Name:  "preview-decrypt",
+								Usage: "Decrypt and preview messages before granting nip04_decrypt",
+							},
+							&cli.IntFlag{
+								Name:  "pow",
+								Usage: "Proof of work bits mined for sign_event requests, 0 to disable",
+							},
+							&cli.StringFlag{
+								Name:  "relay",
+								Usage: "Change the connection relay",
+							},
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Rebind to another stored key (npub1 or hex), clears the permissions",
+							},
+							&cli.BoolFlag{
+								Name:  "keep-grants",
+								Usage: "Keep the permissions when rebinding with --key",
+							},
+						},
+						Action: connectionsEditAction,
+					},
+					{
+						Name:      "rename",
+						Usage:     "Rename connection",
+						ArgsUsage: "<No.|App ID|name> <new name>",
+						Action:    connectionsRenameAction,
+					},
+				},
+			},
+			{
+				Name:  "profile",
+				Usage: "Show and edit profile metadata",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "show",
+						Usage: "Show cached profile",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+						},
+						Action: profileShowAction,
+					},
+					{
+						Name:  "edit",
+						Usage: "Edit profile in $EDITOR or with flags, then sign and publish it",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+							&cli.StringFlag{
+								Name:  "name",
+								Usage: "Set name",
+							},
+							&cli.StringFlag{
+								Name:  "display-name",
+								Usage: "Set display name",
+							},
+							&cli.StringFlag{
+								Name:  "about",
+								Usage: "Set about",
+							},
+							&cli.StringFlag{
+								Name:  "picture",
+								Usage: "Set picture URL",
+							},
+							&cli.StringFlag{
+								Name:  "banner",
+								Usage: "Set banner URL",
+							},
+							&cli.StringFlag{
+								Name:  "website",
+								Usage: "Set website",
+							},
+							&cli.StringFlag{
+								Name:  "nip05",
+								Usage: "Set NIP-05 identifier",
+							},
+							&cli.StringFlag{
+								Name:  "lud16",
+								Usage: "Set lightning address",
+							},
+							&cli.StringSliceFlag{
+								Name:  "set",
+								Usage: "Set any field, key=value",
+							},
+							&cli.StringSliceFlag{
+								Name:  "unset",
+								Usage: "Remove field",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Edit even if no relay answered and no profile is cached",
+							},
+						},
+						Action: profileEditAction,
+					},
+				},
+			},
+			{
+				Name:  "relays",
+				Usage: "Manage NIP-65 relay list",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"l"},
+						Usage:   "List relays",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+						},
+						Action: relaysListAction,
+					},
+					{
+						Name:      "add",
+						Usage:     "Add relays",
+						ArgsUsage: "wss://...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+							&cli.BoolFlag{
+								Name:  "read-only",
+								Usage: "Only read from these relays",
+							},
+							&cli.BoolFlag{
+								Name:  "write-only",
+								Usage: "Only write to these relays",
+							},
+						},
+						Action: relaysAddAction,
+					},
+					{
+						Name:      "remove",
+						Usage:     "Remove relays",
+						ArgsUsage: "wss://...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+						},
+						Action: relaysRemoveAction,
+					},
+					{
+						Name:      "set-read",
+						Usage:     "Mark relays as read relays",
+						ArgsUsage: "wss://...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+							&cli.BoolFlag{
+								Name:  "off",
+								Usage: "Stop reading from these relays",
+							},
+						},
+						Action: relaysSetReadAction,
+					},
+					{
+						Name:      "set-write",
+						Usage:     "Mark relays as write relays",
+						ArgsUsage: "wss://...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+							&cli.BoolFlag{
+								Name:  "off",
+								Usage: "Stop writing to these relays",
+							},
+						},
+						Action: relaysSetWriteAction,
+					},
+					{
+						Name:  "publish",
+						Usage: "Sign and publish the relay list",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+						},
+						Action: relaysPublishAction,
+					},
+					{
+						Name:   "health",
+						Usage:  "Show relay health stats",
+						Action: relaysHealthAction,
+					},
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "Show and edit configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "path",
+						Usage:  "Print config file path",
+						Action: configPathAction,
+					},
+					{
+						Name:   "show",
+						Usage:  "Show configuration",
+						Action: configShowAction,
+					},
+					{
+						Name:      "get",
+						Usage:     "Get a config value",
+						ArgsUsage: "<key>",
+						Action:    configGetAction,
+					},
+					{
+						Name:      "set",
+						Usage:     "Set a config value, lists are comma separated",
+						ArgsUsage: "<key> <value>",
+						Action:    configSetAction,
+					},
+					{
+						Name:      "unset",
+						Usage:     "Unset a config value",
+						ArgsUsage: "<key>",
+						Action:    configUnsetAction,
+					},
+				},
+			},
+			{
+				Name:      "publish",
+				Aliases:   []string{"p"},
+				Usage:     "Sign and publish an event",
+				ArgsUsage: "[content]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "key",
+						Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+					},
+					&cli.IntFlag{
+						Name:    "kind",
+						Aliases: []string{"k"},
+						Usage:   "Event kind",
+						Value:   1,
+					},
+					&cli.StringFlag{
+						Name:    "content",
+						Aliases: []string{"c"},
+						Usage:   "Event content",
+					},
+					&cli.StringSliceFlag{
+						Name:    "tag",
+						Aliases: []string{"t"},
+						Usage:   "Event tag as name=value[;value...], can be repeated",
+					},
+					&cli.BoolFlag{
+						Name:  "stdin",
+						Usage: "Read event JSON from stdin, flags override its fields",
+					},
+					&cli.IntFlag{
+						Name:  "pow",
+						Usage: "Mine a NIP-13 proof of work of this many leading zero bits before signing",
+					},
+					&cli.DurationFlag{
+						Name:  "pow-timeout",
+						Usage: "Give up mining after this, 0 to mine until found",
+						Value: nkcli.DefaultPoWTimeout,
+					},
+				},
+				Action: publishAction,
+			},
+			{
+				Name:  "dm",
+				Usage: "Send and read encrypted direct messages",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "send",
+						Usage:     "Send a direct message, text is read from stdin if omitted",
+						ArgsUsage: "<npub> [text]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+							&cli.BoolFlag{
+								Name:  "nip04",
+								Usage: "Send a legacy NIP-04 message instead of a NIP-17 gift wrap",
+							},
+						},
+						Action: dmSendAction,
+					},
+					{
+						Name:      "read",
+						Usage:     "List conversations, or show the one with npub",
+						ArgsUsage: "[npub]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+							&cli.StringFlag{
+								Name:  "since",
+								Usage: "Only messages after time (unix timestamp, YYYY-MM-DD, RFC3339 or -duration)",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Max events fetched per relay and filter",
+								Value: 100,
+							},
+						},
+						Action: dmReadAction,
+					},
+				},
+			},
+			{
+				Name:      "sign",
+				Usage:     "Sign an unsigned event offline and print it",
+				ArgsUsage: "[file|-|nkcli:...]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "key",
+						Usage: "Pubkey (npub1 or hex), defaults to the event pubkey or choose interactively",
+					},
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Usage:   "Write the signed event to file instead of stdout",
+					},
+					&cli.BoolFlag{
+						Name:  "compact",
+						Usage: "Print the signed event in compact nkcli: form",
+					},
+					&cli.BoolFlag{
+						Name:  "encode",
+						Usage: "Only print the unsigned event in compact nkcli: form, e.g. for a QR code",
+					},
+					&cli.IntFlag{
+						Name:  "pow",
+						Usage: "Mine a NIP-13 proof of work of this many leading zero bits before signing",
+					},
+					&cli.DurationFlag{
+						Name:  "pow-timeout",
+						Usage: "Give up mining after this, 0 to mine until found",
+						Value: nkcli.DefaultPoWTimeout,
+					},
+				},
+				Action: signAction,
+			},
+			{
+				Name:      "verify",
+				Usage:     "Verify id and signature of events, as JSON lines, pretty JSON or an array",
+				ArgsUsage: "[file|-|nkcli:...]",
+				Action:    verifyAction,
+			},
+			{
+				Name:  "events",
+				Usage: "Browse the archive of signed events",
+				Subcommands: []*cli.Command{
+					{
+						Name:    "list",
+						Aliases: []string{"l"},
+						Usage:   "List archived events, newest first",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Only events signed by pubkey (npub1 or hex)",
+							},
+							&cli.IntSliceFlag{
+								Name:    "kind",
+								Aliases: []string{"k"},
+								Usage:   "Only events of kind, can be repeated",
+							},
+							&cli.StringFlag{
+								Name:    "search",
+								Aliases: []string{"s"},
+								Usage:   "Only events containing all these words",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Maximum number of events",
+								Value: 20,
+							},
+						},
+						Action: eventsListAction,
+					},
+					{
+						Name:      "show",
+						Usage:     "Show archived event",
+						ArgsUsage: "<event ID or note1>",
+						Action:    eventsShowAction,
+					},
+					{
+						Name:      "rebroadcast",
+						Usage:     "Publish archived event again",
+						ArgsUsage: "<event ID or note1>",
+						Action:    eventsRebroadcastAction,
+					},
+					{
+						Name:  "export",
+						Usage: "Export archived events as JSONL, oldest first",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "file",
+								Aliases: []string{"f"},
+								Usage:   "Write to file instead of stdout",
+							},
+							&cli.IntSliceFlag{
+								Name:    "kind",
+								Aliases: []string{"k"},
+								Usage:   "Only events of kind, can be repeated",
+							},
+						},
+						Action: eventsExportAction,
+					},
+				},
+			},
+			{
+				Name:  "backup",
+				Usage: "Back up keys",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "shamir",
+						Usage: "Split a key or a stored mnemonic into Shamir shares",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "threshold",
+								Usage: "Number of shares needed to recover",
+								Value: 2,
+							},
+							&cli.IntFlag{
+								Name:  "shares",
+								Usage: fmt.Sprintf("Number of shares, up to %v", nkcli.MaxShares),
+								Value: 3,
+							},
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Pubkey (npub1 or hex), choose interactively if omitted",
+							},
+							&cli.StringFlag{
+								Name:  "seed",
+								Usage: "Split the entropy of this stored mnemonic instead, by fingerprint",
+							},
+						},
+						Action: backupShamirAction,
+					},
+				},
+			},
+			{
+				Name:  "seed",
+				Usage: "Manage stored mnemonics and derive more accounts",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List stored mnemonics and their derived accounts",
+						Action: seedListAction,
+					},
+					{
+						Name:      "show",
+						Usage:     "Decrypt and print a stored mnemonic",
+						ArgsUsage: "[fingerprint]",
+						Action:    seedShowAction,
+					},
+					{
+						Name:      "derive",
+						Usage:     "Derive and save more accounts from a stored mnemonic",
+						ArgsUsage: "[fingerprint]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "account",
+								Usage:    "NIP-06 account N or range N-M, up to 1000 accounts",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "mnemonic-passphrase",
+								Usage: "The seed uses a BIP-39 passphrase, read from $NKCLI_MNEMONIC_PASSPHRASE or asked",
+							},
+							&cli.StringSliceFlag{
+								Name:    "relay",
+								Aliases: []string{"r"},
+								Usage:   "Use specific relay to retrieve metadata",
+								EnvVars: []string{"NKCLI_RELAYS"},
+							},
+						},
+						Action: seedDeriveAction,
+					},
+				},
+			},
+			{
+				Name:  "delegate",
+				Usage: "Manage NIP-26 delegations",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "create",
+						Usage: "Create a delegation token offline",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "to",
+								Usage:    "Delegatee pubkey (npub1 or hex)",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "key",
+								Usage: "Delegator pubkey (npub1 or hex), choose interactively if omitted",
+							},
+							&cli.IntSliceFlag{
+								Name:    "kind",
+								Aliases: []string{"k"},
+								Usage:   "Allowed event kind, can be repeated",
+							},
+							&cli.StringFlag{
+								Name:  "since",
+								Usage: "Valid since (unix timestamp, YYYY-MM-DD, RFC3339 or +duration)",
+							},
+							&cli.StringFlag{
+								Name:  "until",
+								Usage: "Valid until (unix timestamp, YYYY-MM-DD, RFC3339 or +duration)",
+							},
+							&cli.StringFlag{
+								Name:  "conditions",
+								Usage: "Raw NIP-26 conditions string, e.g. kind=1&created_at<1700000000",
+							},
+							&cli.DurationFlag{
+								Name:  "max-lifetime",
+								Usage: "Refuse delegations valid for longer than this, 0 to disable",
+								Value: nkcli.DefaultMaxDelegationLifetime,
+							},
+						},
+						Action: delegateCreateAction,
+					},
+					{
+						Name:    "list",
+						Aliases: []string{"l"},
+						Usage:   "List issued delegations",
+						Action:  delegateListAction,
+					},
+				},
+			},
 		},
 	}
 
+	app.OnUsageError = onUsageError
+	setUsageErrors(app.Commands)
+
 	if err := app.Run(os.Args); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(reportError(err))
 	}
 }
 
 func getDBPath() (string, error) {
-	dir, err := os.UserHomeDir()
+	home, err := os.UserHomeDir()
 
 	if err != nil {
 		return "", err
 	}
 
-	return dir + "/.nkclidb", nil
+	legacy := filepath.Join(home, ".nkclidb")
+
+	if _, err = os.Stat(legacy); err == nil {
+		return legacy, nil
+	}
+
+	dir, err := nkcli.XDGDataHome()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "nkcli", "nkcli.db"), nil
+}
+
+func loadConfig(c *cli.Context) error {
+	conf, err := nkcli.LoadConfig(c.String("config"))
+
+	if err != nil {
+		return err
+	}
+
+	config = conf
+
+	if err = setupOutput(c); err != nil {
+		return err
+	}
+
+	if err = setupPrompter(c); err != nil {
+		return err
+	}
+
+	if !c.IsSet("db") && len(conf.DB) > 0 {
+		p := conf.DB
+
+		if strings.HasPrefix(p, "~/") {
+			home, err := os.UserHomeDir()
+
+			if err != nil {
+				return err
+			}
+
+			p = filepath.Join(home, p[2:])
+		}
+
+		return c.Set("db", p)
+	}
+
+	return nil
 }
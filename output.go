@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var (
+	outputFormat = outputTable
+	// stdout keeps the real standard output, in json and yaml mode os.Stdout
+	// points to stderr so prompts and progress don't mix with the result.
+	stdout = os.Stdout
+)
+
+var (
+	errUsage         = errors.New("Usage")
+	errInvalidOutput = errors.New("Invalid output format, use table, json or yaml")
+)
+
+type usageError struct {
+	error
+}
+
+func (e usageError) Is(target error) bool {
+	return target == errUsage
+}
+
+func (e usageError) Unwrap() error {
+	return e.error
+}
+
+func setupOutput(c *cli.Context) error {
+	format := config.Output
+
+	if c.IsSet("output") || len(format) == 0 {
+		format = c.String("output")
+	}
+
+	switch format {
+	case outputTable:
+	case outputJSON, outputYAML:
+		os.Stdout = os.Stderr
+	default:
+		return usageError{errors.Join(errInvalidOutput, errors.New(format))}
+	}
+
+	outputFormat = format
+
+	return nil
+}
+
+// render prints v in the selected machine readable format, or calls table
+// for the human readable one.
+func render(v any, table func()) error {
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+
+		return enc.Encode(v)
+	case outputYAML:
+		buf, err := toYAML(v)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = stdout.Write(buf)
+
+		return err
+	}
+
+	table()
+
+	return nil
+}
+
+// toYAML goes through JSON so both formats share the json tags and field order.
+func toYAML(v any) ([]byte, error) {
+	buf, err := json.Marshal(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(yaml.Node)
+
+	if err = yaml.Unmarshal(buf, node); err != nil {
+		return nil, err
+	}
+
+	blockStyle(node)
+
+	return yaml.Marshal(node)
+}
+
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+var mainErrorClasses = []nkcli.ErrorClass{
+	{Code: nkcli.CodeUsage, Errs: []error{
+		errUsage, errInvalidKeyNo, errInvalidTimeArg, errInvalidConnNo, errAmbiguousConn, errUnknownMethod,
+		errInvalidDelegatee, errInvalidPeer, errEmptyDM, errEventIdRequired, errInvalidMnemonic,
+		errProfileNotJSON, errInvalidField, errInvalidTag, errInvalidEventArg, errEmptyEvent,
+		errNoRelayArgs, errRelayUnusable, errInvalidConditions, errInvalidCompact, errPubkeyMismatch,
+		errInvalidRelayUrl, errNoEvents, errPassphraseEnvUnset, errPassphraseSources,
+		errMnemonicPassphraseMismatch, errInvalidSeedNo, errVanityPrefixes, errVanityNoMnemonic,
+		errImportSources, errInvalidImportFile, errInvalidWatchKey, errWatchConnection,
+	}},
+	{Code: nkcli.CodeNotFound, Errs: []error{errUnknownKey, errNoKeys, errNoSeeds, errUnknownConnection, errNoConnections, errRelayNotInList}},
+	{Code: nkcli.CodeRejected, Errs: []error{
+		errSignRejected, errPublishRejected, errConnectionsKept, errNewerProfile, errProfileNoChange, errNoRelayDraft,
+		errInvalidID, errInvalidSig, errInvalidEvents, errMnemonicCheck,
+		errVanityCancelled, errVanityTimeout, errPoWCancelled, errPoWTimeout,
+	}},
+	{Code: nkcli.CodeNetwork, Errs: []error{errRelayUnreachable, errNoRelayAccepted, errProfileUnknown}},
+}
+
+// reportError prints err in the selected output format and returns the exit code.
+func reportError(err error) int {
+	code := nkcli.ClassifyError(err, mainErrorClasses...)
+	rec := &nkcli.ErrorRecord{Code: code, Message: err.Error(), ExitCode: nkcli.ExitCodes[code]}
+
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stderr)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]any{"error": rec})
+	case outputYAML:
+		if buf, err := toYAML(map[string]any{"error": rec}); err == nil {
+			os.Stderr.Write(buf)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+
+	return rec.ExitCode
+}
+
+// setUsageErrors makes flag parsing errors of every command exit as usage errors.
+func setUsageErrors(cmds []*cli.Command) {
+	for _, cmd := range cmds {
+		cmd.OnUsageError = onUsageError
+		setUsageErrors(cmd.Subcommands)
+	}
+}
+
+func onUsageError(c *cli.Context, err error, isSubcommand bool) error {
+	return usageError{err}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errPoWCancelled = errors.New("Proof of work mining cancelled")
+	errPoWTimeout   = errors.New("Proof of work mining timed out")
+)
+
+// minePoW mines a nonce tag for event to the --pow difficulty, until found,
+// interrupted or --pow-timeout. It does nothing without --pow.
+func minePoW(c *cli.Context, event *nostr.Event) error {
+	target := c.Int("pow")
+
+	if target == 0 {
+		return nil
+	}
+
+	if err := nkcli.CheckPoW(target); err != nil {
+		return usageError{err}
+	}
+
+	fmt.Printf("Mining proof of work of %v bits, press Ctrl-C to cancel...\n", target)
+
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+	defer stop()
+
+	if t := c.Duration("pow-timeout"); t > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := nkcli.MinePoW(ctx, event, target, runtime.NumCPU())
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return errPoWTimeout
+	case errors.Is(err, context.Canceled):
+		return errPoWCancelled
+	case err != nil:
+		return err
+	}
+
+	fmt.Printf("Found %v bits in %v.\n", nkcli.Difficulty(event.ID), time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
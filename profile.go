@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errNewerProfile    = errors.New("A newer profile was found on relays, run 'nkcli update' first")
+	errProfileUnknown  = errors.New("No relay answered and no profile is cached, use --force to start from an empty profile")
+	errProfileNotJSON  = errors.New("Profile must be a JSON object")
+	errProfileNoChange = errors.New("Profile not changed")
+	errInvalidField    = errors.New("Invalid field, use key=value")
+)
+
+var profileFields = map[string]string{
+	"name":         "name",
+	"display-name": "display_name",
+	"about":        "about",
+	"picture":      "picture",
+	"banner":       "banner",
+	"website":      "website",
+	"nip05":        "nip05",
+	"lud16":        "lud16",
+}
+
+func profileShowAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	rec := map[string]any{"pubkey": key.Pubkey, "profile": nil, "updated_at": 0}
+	e, err := db.GetMetadataEvent(key.Pubkey)
+
+	if err != nil {
+		return render(rec, func() {
+			fmt.Println("No profile cached, run 'nkcli update' first.")
+		})
+	}
+
+	profile, err := decodeProfile(e.Content)
+
+	if err != nil {
+		return err
+	}
+
+	rec["profile"], rec["updated_at"] = profile, e.CreatedAt.Unix()
+
+	return render(rec, func() {
+		str, _ := json.MarshalIndent(profile, "", "  ")
+		fmt.Printf("%s\n\nUpdated at: %v\n", str, formatTime(&e.CreatedAt))
+	})
+}
+
+func profileEditAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	router := newRouter(c, db)
+	cached, _ := db.GetMetadataEvent(key.Pubkey)
+
+	fmt.Print("Checking relays for newer profile...\n")
+
+	ctx, cancel := context.WithTimeout(c.Context, 10*time.Second)
+	latest, err := router.FetchLatestAnswered(ctx, nostr.Filter{
+		Kinds:   []int{0},
+		Authors: []string{key.Pubkey},
+		Limit:   1,
+	})
+	cancel()
+
+	// Starting from nothing would publish over a profile relays couldn't tell about.
+	if err != nil && cached == nil && !c.Bool("force") {
+		return errors.Join(errProfileUnknown, err)
+	}
+
+	if latest != nil && (cached == nil || latest.CreatedAt.After(cached.CreatedAt)) {
+		return errNewerProfile
+	}
+
+	profile := make(map[string]any)
+
+	if cached != nil {
+		if profile, err = decodeProfile(cached.Content); err != nil {
+			return err
+		}
+	}
+
+	if profileFlagsSet(c) {
+		err = applyProfileFlags(c, profile)
+	} else {
+		profile, err = editProfile(profile)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(profile)
+
+	if err != nil {
+		return err
+	}
+
+	if cached != nil && string(content) == cached.Content {
+		return errProfileNoChange
+	}
+
+	event := &nostr.Event{
+		PubKey:    key.Pubkey,
+		Kind:      0,
+		CreatedAt: time.Now(),
+		Tags:      nostr.Tags{},
+		Content:   string(content),
+	}
+
+	fmt.Printf("\nEvent detail:\n\n%v\n", nkcli.DescribeEvent(db, event))
+
+	info, err := unlockKey(db, key.Pubkey)
+
+	if err != nil {
+		return err
+	}
+
+	if err = event.Sign(info.Privkey); err != nil {
+		return err
+	}
+
+	archiveEvent(c, db, event, "nkcli")
+
+	fmt.Print("Publishing to relays...\n\n")
+
+	if err = renderPublish(event, router.Publish(c.Context, event)); err != nil {
+		return err
+	}
+
+	return db.SaveMetadataEvent(event)
+}
+
+// decodeProfile keeps numbers as json.Number, unknown fields are written
+// back as they were.
+func decodeProfile(content string) (map[string]any, error) {
+	profile := make(map[string]any)
+
+	if len(content) == 0 {
+		return profile, nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+
+	if err := dec.Decode(&profile); err != nil || profile == nil || dec.More() {
+		return nil, errProfileNotJSON
+	}
+
+	return profile, nil
+}
+
+func profileFlagsSet(c *cli.Context) bool {
+	for flag := range profileFields {
+		if c.IsSet(flag) {
+			return true
+		}
+	}
+
+	return c.IsSet("set") || c.IsSet("unset")
+}
+
+func applyProfileFlags(c *cli.Context, profile map[string]any) error {
+	for flag, field := range profileFields {
+		if c.IsSet(flag) {
+			profile[field] = c.String(flag)
+		}
+	}
+
+	for _, kv := range c.StringSlice("set") {
+		k, v, ok := strings.Cut(kv, "=")
+
+		if !ok || len(k) == 0 {
+			return errors.Join(errInvalidField, errors.New(kv))
+		}
+
+		profile[k] = v
+	}
+
+	for _, k := range c.StringSlice("unset") {
+		delete(profile, k)
+	}
+
+	return nil
+}
+
+func editProfile(profile map[string]any) (map[string]any, error) {
+	editor := os.Getenv("EDITOR")
+
+	if len(editor) == 0 {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "nkcli-profile-*.json")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(f.Name())
+
+	buf, err := json.MarshalIndent(profile, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = f.Write(buf)
+	f.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	args := append(strings.Fields(editor), f.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if err = cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	buf, err = os.ReadFile(f.Name())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeProfile(string(buf))
+}
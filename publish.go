@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errInvalidTag      = errors.New("Invalid tag, use name=value[;value...]")
+	errInvalidEventArg = errors.New("Invalid event JSON")
+	errEmptyEvent      = errors.New("Event has no content and no tags")
+)
+
+type unsignedEvent struct {
+	PubKey    string     `json:"pubkey"`
+	Kind      *int       `json:"kind"`
+	CreatedAt *int64     `json:"created_at"`
+	Tags      nostr.Tags `json:"tags"`
+	Content   string     `json:"content"`
+}
+
+func publishAction(c *cli.Context) error {
+	event, err := eventFromFlags(c)
+
+	if err != nil {
+		return err
+	}
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	event.PubKey = key.Pubkey
+
+	if err = minePoW(c, event); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nEvent detail:\n\n%v\n", nkcli.DescribeEvent(db, event))
+
+	info, err := unlockKey(db, key.Pubkey)
+
+	if err != nil {
+		return err
+	}
+
+	if err = event.Sign(info.Privkey); err != nil {
+		return err
+	}
+
+	archiveEvent(c, db, event, "nkcli")
+
+	recipients := make([]string, 0)
+
+	for _, t := range event.Tags.GetAll([]string{"p", ""}) {
+		recipients = append(recipients, t.Value())
+	}
+
+	fmt.Printf("Publishing %v to relays...\n\n", event.ID)
+
+	return renderPublish(event, newRouter(c, db).Publish(c.Context, event, recipients...))
+}
+
+func eventFromFlags(c *cli.Context) (*nostr.Event, error) {
+	event := &nostr.Event{
+		Kind:      1,
+		CreatedAt: time.Now(),
+		Tags:      nostr.Tags{},
+	}
+
+	if c.Bool("stdin") {
+		e, err := readUnsignedEvent(os.Stdin)
+
+		if err != nil {
+			return nil, err
+		}
+
+		event = e
+	}
+
+	if c.IsSet("kind") {
+		event.Kind = c.Int("kind")
+	}
+
+	if c.IsSet("content") {
+		event.Content = c.String("content")
+	} else if c.Args().Len() > 0 {
+		event.Content = strings.Join(c.Args().Slice(), " ")
+	}
+
+	for _, s := range c.StringSlice("tag") {
+		t, err := parseTagArg(s)
+
+		if err != nil {
+			return nil, err
+		}
+
+		event.Tags = append(event.Tags, t)
+	}
+
+	if len(event.Content) == 0 && len(event.Tags) == 0 {
+		return nil, errEmptyEvent
+	}
+
+	return event, nil
+}
+
+func readUnsignedEvent(r io.Reader) (*nostr.Event, error) {
+	in := new(unsignedEvent)
+
+	if err := json.NewDecoder(r).Decode(in); err != nil {
+		return nil, errors.Join(errInvalidEventArg, err)
+	}
+
+	event := &nostr.Event{
+		PubKey:    in.PubKey,
+		Kind:      1,
+		CreatedAt: time.Now(),
+		Tags:      in.Tags,
+		Content:   in.Content,
+	}
+
+	if in.Kind != nil {
+		event.Kind = *in.Kind
+	}
+
+	if in.CreatedAt != nil {
+		event.CreatedAt = time.Unix(*in.CreatedAt, 0)
+	}
+
+	if event.Tags == nil {
+		event.Tags = nostr.Tags{}
+	}
+
+	return event, nil
+}
+
+func parseTagArg(s string) (nostr.Tag, error) {
+	name, values, ok := strings.Cut(s, "=")
+
+	if !ok || len(name) == 0 {
+		return nil, errors.Join(errInvalidTag, errors.New(s))
+	}
+
+	tag := nostr.Tag{name}
+
+	for _, v := range strings.Split(values, ";") {
+		if name == "p" && len(tag) == 1 {
+			if list := nkcli.SerializeKeys([]string{v}); len(list) > 0 {
+				v = list[0]
+			}
+		}
+
+		tag = append(tag, v)
+	}
+
+	return tag, nil
+}
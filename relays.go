@@ -0,0 +1,369 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errNoRelayArgs     = errors.New("You need pass at least one relay URL")
+	errRelayNotInList  = errors.New("Relay is not in the list")
+	errRelayUnusable   = errors.New("Relay must be read or write, use 'nkcli relays remove' instead")
+	errNoRelayDraft    = errors.New("Relay list has not been changed, nothing to publish")
+	errNoRelayAccepted = errors.New("No relay accepted the event")
+)
+
+func relaysListAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	m, unpublished := relayDraft(db, key)
+	rec := &nkcli.RelayListRecord{
+		Pubkey:      key.Pubkey,
+		Relays:      m.Records(),
+		Unpublished: unpublished,
+	}
+
+	return render(rec, func() {
+		printRelayMap(m)
+
+		if rec.Unpublished {
+			fmt.Println("\nThis list has unpublished changes, run 'nkcli relays publish' to publish it.")
+		}
+	})
+}
+
+func relaysAddAction(c *cli.Context) error {
+	return editRelays(c, func(m nkcli.RelayMap, url string) error {
+		m[url] = &nkcli.RelayAttr{
+			Read:  !c.Bool("write-only"),
+			Write: !c.Bool("read-only"),
+		}
+
+		return nil
+	})
+}
+
+func relaysRemoveAction(c *cli.Context) error {
+	return editRelays(c, func(m nkcli.RelayMap, url string) error {
+		if _, ok := m[url]; !ok {
+			return errors.Join(errRelayNotInList, errors.New(url))
+		}
+
+		delete(m, url)
+
+		return nil
+	})
+}
+
+func relaysSetReadAction(c *cli.Context) error {
+	return editRelays(c, func(m nkcli.RelayMap, url string) error {
+		attr, ok := m[url]
+
+		if !ok {
+			return errors.Join(errRelayNotInList, errors.New(url))
+		}
+
+		if attr.Read = !c.Bool("off"); !attr.Read && !attr.Write {
+			return errRelayUnusable
+		}
+
+		return nil
+	})
+}
+
+func relaysSetWriteAction(c *cli.Context) error {
+	return editRelays(c, func(m nkcli.RelayMap, url string) error {
+		attr, ok := m[url]
+
+		if !ok {
+			return errors.Join(errRelayNotInList, errors.New(url))
+		}
+
+		if attr.Write = !c.Bool("off"); !attr.Read && !attr.Write {
+			return errRelayUnusable
+		}
+
+		return nil
+	})
+}
+
+func editRelays(c *cli.Context, edit func(nkcli.RelayMap, string) error) error {
+	if c.Args().Len() == 0 {
+		return errNoRelayArgs
+	}
+
+	for _, url := range c.Args().Slice() {
+		if err := checkRelayUrl(url); err != nil {
+			return err
+		}
+	}
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	m, _ := relayDraft(db, key)
+
+	for _, url := range c.Args().Slice() {
+		if err = edit(m, nkcli.NormalizeRelayURL(url)); err != nil {
+			return err
+		}
+	}
+
+	if _, err = db.SaveRelayDraft(key.Pubkey, m); err != nil {
+		return err
+	}
+
+	rec := &nkcli.RelayListRecord{Pubkey: key.Pubkey, Relays: m.Records(), Unpublished: true}
+
+	return render(rec, func() {
+		printRelayMap(m)
+
+		fmt.Println("\nRun 'nkcli relays publish' to sign and publish your relay list.")
+	})
+}
+
+func relaysPublishAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	key, err := chooseKey(db, c.String("key"))
+
+	if err != nil {
+		return err
+	}
+
+	event, err := db.GetRelayDraft(key.Pubkey)
+
+	if err != nil {
+		return errNoRelayDraft
+	}
+
+	event.CreatedAt = time.Now()
+
+	fmt.Printf("\nEvent detail:\n\n%v\n", nkcli.DescribeEvent(db, event))
+
+	info, err := unlockKey(db, key.Pubkey)
+
+	if err != nil {
+		return err
+	}
+
+	if err = event.Sign(info.Privkey); err != nil {
+		return err
+	}
+
+	archiveEvent(c, db, event, "nkcli")
+
+	router := newRouter(c, db)
+	relays := uniqueRelays(append(router.OutboxRelays(key.Pubkey), router.Boots...))
+
+	fmt.Print("Publishing to relays...\n\n")
+
+	if err = renderPublish(event, router.PublishTo(c.Context, relays, event)); err != nil {
+		return err
+	}
+
+	if err = db.SaveRelayEvent(event); err != nil {
+		return err
+	}
+
+	return db.DeleteRelayDraft(key.Pubkey)
+}
+
+// relayDraft returns a copy of the unpublished relay list of key, or of its
+// relay list in use when there's no draft.
+func relayDraft(db *nkcli.DB, key *nkcli.KeyInfo) (nkcli.RelayMap, bool) {
+	if e, err := db.GetRelayDraft(key.Pubkey); err == nil {
+		if m, err := nkcli.RelayMapOf(e); err == nil {
+			return m, true
+		}
+	}
+
+	m := make(nkcli.RelayMap, len(key.Relays))
+
+	for u, attr := range key.Relays {
+		a := *attr
+		m[u] = &a
+	}
+
+	return m, false
+}
+
+func relaysHealthAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	stats := db.RelayStats()
+	urls := make([]string, 0, len(stats))
+
+	for u := range stats {
+		urls = append(urls, u)
+	}
+
+	sort.Strings(urls)
+
+	now := time.Now()
+	records := make([]*nkcli.RelayHealthRecord, len(urls))
+
+	for i, u := range urls {
+		records[i] = &nkcli.RelayHealthRecord{RelayStats: stats[u], Healthy: stats[u].Healthy(now)}
+	}
+
+	return render(records, func() {
+		if len(records) == 0 {
+			fmt.Println("No relay has been used yet.")
+			return
+		}
+
+		for _, r := range records {
+			mark := "✅"
+
+			if !r.Healthy {
+				mark = "❌"
+			}
+
+			fmt.Printf("  %v %v\n     OK: %v  Failed: %v  Latency: %vms\n", mark, r.URL, r.Successes, r.Failures, r.Latency)
+
+			if r.Consecutive > 0 {
+				fmt.Printf("     Last error: %v (%v)\n", r.LastError, time.Unix(r.LastFailure, 0).Format(time.DateTime))
+			}
+
+			fmt.Println()
+		}
+	})
+}
+
+func printRelayMap(m nkcli.RelayMap) {
+	if len(m) == 0 {
+		fmt.Println("No relays, add one with 'nkcli relays add'.")
+		return
+	}
+
+	urls := make([]string, 0, len(m))
+
+	for u := range m {
+		urls = append(urls, u)
+	}
+
+	sort.Strings(urls)
+
+	fmt.Printf("You have %v relays:\n\n", len(urls))
+
+	for _, u := range urls {
+		modes := make([]string, 0)
+
+		if m[u].Read {
+			modes = append(modes, "read")
+		}
+
+		if m[u].Write {
+			modes = append(modes, "write")
+		}
+
+		fmt.Printf("  %v (%v)\n", u, strings.Join(modes, "/"))
+	}
+}
+
+func printPublishResults(results []*nkcli.PublishResult) {
+	for _, res := range results {
+		if res.Err != nil {
+			fmt.Printf("  %v: %v\n", res.Relay, res.Err)
+			continue
+		}
+
+		if len(res.Message) > 0 {
+			fmt.Printf("  %v: %v (%v)\n", res.Relay, res.Status, res.Message)
+		} else {
+			fmt.Printf("  %v: %v\n", res.Relay, res.Status)
+		}
+
+		for _, n := range res.Notices {
+			fmt.Printf("    NOTICE: %v\n", n)
+		}
+	}
+}
+
+func acceptedCount(results []*nkcli.PublishResult) (ok int) {
+	for _, res := range results {
+		if res.Err == nil && res.Status != nostr.PublishStatusFailed {
+			ok++
+		}
+	}
+
+	return
+}
+
+// renderPublish renders event with its per relay results and fails when no relay accepted it.
+func renderPublish(event *nostr.Event, results []*nkcli.PublishResult) error {
+	rec := &nkcli.PublishedRecord{Event: event, Results: nkcli.PublishRecords(results)}
+
+	if err := render(rec, func() { printPublishResults(results) }); err != nil {
+		return err
+	}
+
+	if acceptedCount(results) == 0 {
+		return errNoRelayAccepted
+	}
+
+	return nil
+}
+
+func checkRelayUrl(url string) error {
+	if !strings.HasPrefix(url, "ws://") && !strings.HasPrefix(url, "wss://") {
+		return errors.Join(errInvalidRelayUrl, errors.New(url))
+	}
+
+	return nil
+}
+
+func uniqueRelays(list []string) []string {
+	result := make([]string, 0, len(list))
+
+	for _, url := range list {
+		if url = nostr.NormalizeURL(url); !contains(result, url) {
+			result = append(result, url)
+		}
+	}
+
+	return result
+}
@@ -2,9 +2,7 @@ package main
 
 import (
 	"encoding/hex"
-	"errors"
 	"fmt"
-	"strconv"
 
 	nkcli "github.com/mdzz-club/nkcli/internal"
 	"github.com/urfave/cli/v2"
@@ -17,69 +15,73 @@ func removeAction(c *cli.Context) error {
 		return err
 	}
 
+	var removed []string
+
 	if c.Args().Len() == 0 {
-		return removeManually(db)
+		removed, err = removeManually(db)
 	} else {
-		return removeKeys(db, c.Args().Slice())
+		removed, err = removeKeys(db, c.Args().Slice())
+	}
+
+	if err != nil {
+		return err
 	}
+
+	return render(map[string][]string{"removed": removed}, func() {})
 }
 
-func removeManually(db *nkcli.DB) error {
+func removeManually(db *nkcli.DB) ([]string, error) {
 	list, err := db.List()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(list) == 0 {
 		fmt.Println("You don't have any keys, goto generate or import one.")
-		return nil
+		return []string{}, nil
 	}
 
 	fmt.Printf("You have %v keys:\n\n", len(list))
 
 	nkcli.PrintKeyList(list)
 
-	fmt.Print("\n  ⭐️ Choose one key: ")
-
-	var line string
-	_, err = fmt.Scanln(&line)
-
-	if err != nil {
-		return err
-	}
+	fmt.Println()
 
-	n, err := strconv.Atoi(line)
+	n, err := prompter.Choose("  ⭐️ Choose one key: ", len(list))
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if n < 1 || n > len(list) {
-		return errors.New("Invalid No.")
+	if n < 0 {
+		return nil, errInvalidKeyNo
 	}
 
-	key := list[n-1]
-	fmt.Printf("Do you want to DELETE '%v'? [y/n]", key.Pubkey)
+	key := list[n]
 
-	if nkcli.Scanline() != "y" {
-		return nil
+	if ok, err := prompter.Confirm(fmt.Sprintf("Do you want to DELETE '%v'? [y/n]", key.Pubkey)); err != nil || !ok {
+		return []string{}, err
 	}
 
 	kb, err := hex.DecodeString(key.Pubkey)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return db.Remove(kb)
+	if err = db.Remove(kb); err != nil {
+		return nil, err
+	}
+
+	return []string{key.Pubkey}, nil
 }
 
-func removeKeys(db *nkcli.DB, keys []string) error {
+func removeKeys(db *nkcli.DB, keys []string) ([]string, error) {
 	list := nkcli.SerializeKeys(keys)
 
 	if len(list) == 0 {
-		return nil
+		return []string{}, nil
 	}
 
 	fmt.Print("Do you want to DELETE these keys?\n\n")
@@ -88,25 +90,26 @@ func removeKeys(db *nkcli.DB, keys []string) error {
 		fmt.Printf("  %v. %v\n", i+1, it)
 	}
 
-	fmt.Print("\n[y/n]")
-
-	if nkcli.Scanline() != "y" {
-		return nil
+	if ok, err := prompter.Confirm("\n[y/n]"); err != nil || !ok {
+		return []string{}, err
 	}
 
+	removed := make([]string, 0, len(list))
+
 	for _, k := range list {
 		id, err := hex.DecodeString(k)
 
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		if db.Remove(id) != nil {
-			return err
+		if err = db.Remove(id); err != nil {
+			return removed, err
 		}
 
-		fmt.Printf("Key '%v' has been deleted", k)
+		removed = append(removed, k)
+		fmt.Printf("Key '%v' has been deleted\n", k)
 	}
 
-	return nil
+	return removed, nil
 }
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errNoSeeds       = errors.New("You don't have any stored mnemonic, use generate or import with --store-mnemonic")
+	errInvalidSeedNo = errors.New("Invalid seed No.")
+)
+
+func seedListAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	seeds, err := db.ListSeeds()
+
+	if err != nil {
+		return err
+	}
+
+	keys, err := db.List()
+
+	if err != nil {
+		return err
+	}
+
+	records := nkcli.SeedRecords(seeds, keys)
+
+	return render(records, func() {
+		if len(records) == 0 {
+			fmt.Println("You don't have any stored mnemonic.")
+			return
+		}
+
+		printSeeds(records)
+	})
+}
+
+func seedShowAction(c *cli.Context) error {
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	seed, err := chooseSeed(db, c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	if ok, err := prompter.Confirm(fmt.Sprintf("Anyone who sees the mnemonic of %v owns all its keys, show it? [y/n]", seed.Fingerprint)); err != nil || !ok {
+		return err
+	}
+
+	mnemonic, err := unlockSeed(seed)
+
+	if err != nil {
+		return err
+	}
+
+	return render(map[string]string{"fingerprint": seed.Fingerprint, "mnemonic": mnemonic}, func() {
+		fmt.Printf("\n%v\n", mnemonic)
+	})
+}
+
+func seedDeriveAction(c *cli.Context) error {
+	accounts, err := nkcli.ParseAccounts(c.String("account"))
+
+	if err != nil {
+		return usageError{err}
+	}
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	seed, err := chooseSeed(db, c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	mnemonic, err := unlockSeed(seed)
+
+	if err != nil {
+		return err
+	}
+
+	mnemonicPass, err := mnemonicPassphrase(c, false)
+
+	if err != nil {
+		return err
+	}
+
+	buf := nkcli.SeedFromWords(mnemonic, mnemonicPass)
+
+	if err = nkcli.CheckSeed(buf, seed.Fingerprint); err != nil {
+		return err
+	}
+
+	keys, _, err := importAccounts(db, buf, accounts)
+
+	if err != nil {
+		return err
+	}
+
+	return updateNewKeys(c, db, keys)
+}
+
+func unlockSeed(seed *nkcli.Seed) (string, error) {
+	pass, err := prompter.ReadPassphrase(seed.Pubkey, nkcli.PurposeUnlock, "Enter your passphrase to unlock your mnemonic:")
+
+	if err != nil {
+		return "", err
+	}
+
+	return seed.Unlock(pass)
+}
+
+func chooseSeed(db *nkcli.DB, fingerprint string) (*nkcli.Seed, error) {
+	if len(fingerprint) > 0 {
+		return db.GetSeed(fingerprint)
+	}
+
+	seeds, err := db.ListSeeds()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(seeds) {
+	case 0:
+		return nil, errNoSeeds
+	case 1:
+		return seeds[0], nil
+	}
+
+	keys, err := db.List()
+
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("You have %v stored mnemonics:\n\n", len(seeds))
+
+	printSeeds(nkcli.SeedRecords(seeds, keys))
+
+	n, err := prompter.Choose("  🌱 Choose one: ", len(seeds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		return nil, errInvalidSeedNo
+	}
+
+	return seeds[n], nil
+}
+
+func printSeeds(records []*nkcli.SeedRecord) {
+	for i, r := range records {
+		accounts := make([]string, len(r.Accounts))
+
+		for j, a := range r.Accounts {
+			accounts[j] = fmt.Sprint(a)
+		}
+
+		fmt.Printf("  %v. 🌱 %v\n     Accounts: %v\n     Stored: %v\n\n", i+1, r.Fingerprint, strings.Join(accounts, ", "), time.Unix(r.CreatedAt, 0).Format(time.DateTime))
+	}
+}
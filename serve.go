@@ -2,21 +2,26 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"runtime"
 	"sync"
 	"time"
 
 	nkcli "github.com/mdzz-club/nkcli/internal"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip04"
-	"github.com/nbd-wtf/go-nostr/nip26"
 	"github.com/urfave/cli/v2"
 )
 
+var (
+	errInvalidConditions = errors.New("Invalid delegation conditions")
+)
+
 func serveAction(c *cli.Context) error {
 	dbpath := c.String("db")
+	maxLifetime := c.Duration("max-delegation-lifetime")
 
 	db, err := nkcli.Open(dbpath)
 
@@ -24,6 +29,8 @@ func serveAction(c *cli.Context) error {
 		return err
 	}
 
+	flushQueue(c, db)
+
 	conns, err := db.ListConnection()
 
 	if err != nil {
@@ -44,6 +51,7 @@ Run 'nkcli help' get more.
 	fmt.Printf("Serving %v connections...\n", len(conns))
 
 	ctx := context.WithValue(c.Context, "db", db)
+	ctx = context.WithValue(ctx, "prompter", prompter)
 	ctx, cancel := context.WithCancel(ctx)
 	signalCh := make(chan os.Signal, 1)
 	cMap := make(map[string]context.CancelFunc)
@@ -54,6 +62,7 @@ Run 'nkcli help' get more.
 	}()
 
 	reqCh := make(chan *nkcli.ConnectRequest, 10)
+	minedCh := make(chan *minedRequest)
 	wg := new(sync.WaitGroup)
 
 	for _, item := range conns {
@@ -68,9 +77,16 @@ Run 'nkcli help' get more.
 			select {
 			case <-ctx.Done():
 				return
+			case m := <-minedCh:
+				fmt.Printf("\n  ⛏  Request ID: %v mined %v bits of proof of work\n", m.req.ID, nkcli.Difficulty(m.ev.ID))
+
+				approveSign(c, db, m.req, m.ev)
 			case req := <-reqCh:
 				fmt.Printf("\n  🔔 Request ID: %v Method: %v\n", req.ID, req.Method)
 
+				req.Conn.LastActive = time.Now().Unix()
+				db.TouchConnection(req.Conn.AppID, req.Conn.LastActive)
+
 				switch req.Method {
 				case "describe":
 					req.Response([]string{"describe", "get_public_key", "sign_event", "disconnect", "nip04_encrypt", "nip04_decrypt", "delegate"})
@@ -89,21 +105,19 @@ Run 'nkcli help' get more.
 						continue
 					}
 
-					fmt.Printf("Event detail:\n\n")
-
-					printEvent(ev)
-
-					if err = req.CheckAllow("sign_event"); err != nil {
-						req.Response(err)
-						continue
+					if len(ev.PubKey) == 0 {
+						ev.PubKey = req.Conn.PubKey
 					}
 
-					if err = ev.Sign(req.Conn.KeyInfo.Privkey); err != nil {
-						req.Response(err)
+					// Mining may take a while, other requests go on meanwhile.
+					// The event is shown and approved once mined, as it's signed.
+					if req.Conn.PoW > 0 {
+						fmt.Printf("Mining proof of work of %v bits before asking to sign...\n", req.Conn.PoW)
+						go mineRequest(ctx, req, ev, minedCh)
 						continue
 					}
 
-					req.Response(ev)
+					approveSign(c, db, req, ev)
 				case "disconnect":
 					fmt.Printf("%v Request disconnect.", req.Conn.AppID)
 
@@ -146,13 +160,15 @@ Run 'nkcli help' get more.
 
 					req.Response(ciphered)
 				case "nip04_decrypt":
-					pub, plain := req.Params[0].(string), req.Params[1].(string)
+					pub, ciphered := req.Params[0].(string), req.Params[1].(string)
 
-					fmt.Printf("Decrypt message from %v\n", pub)
+					if !req.Conn.PreviewDecrypt {
+						fmt.Printf("Decrypt message from %v\n", pub)
 
-					if err = req.CheckAllow("nip04_decrypt"); err != nil {
-						req.Response(err)
-						continue
+						if err = req.CheckAllow("nip04_decrypt"); err != nil {
+							req.Response(err)
+							continue
+						}
 					}
 
 					shared, err := nip04.ComputeSharedSecret(pub, req.Conn.KeyInfo.Privkey)
@@ -162,42 +178,82 @@ Run 'nkcli help' get more.
 						continue
 					}
 
-					text, err := nip04.Decrypt(plain, shared)
+					text, err := nip04.Decrypt(ciphered, shared)
 
 					if err != nil {
 						req.Response(err)
 						continue
 					}
 
+					if req.Conn.PreviewDecrypt {
+						fmt.Printf("Decrypt message:\n\n")
+						fmt.Print(nkcli.DescribeMessage(db, pub, text))
+
+						if err = req.CheckAllowPeer("nip04_decrypt", pub); err != nil {
+							req.Response(err)
+							continue
+						}
+					}
+
 					req.Response([]string{text})
 				case "delegate":
-					delegatee, conds := req.Params[0].(string), req.Params[1].(map[string]any)
-					kind := int(conds["kind"].(float64))
-					since := time.Unix(int64(conds["since"].(float64)), 0)
-					until := time.Unix(int64(conds["until"].(float64)), 0)
+					if len(req.Params) < 2 {
+						req.Response(errInvalidConditions)
+						continue
+					}
+
+					delegatee, ok := req.Params[0].(string)
+
+					if !ok {
+						req.Response(errInvalidConditions)
+						continue
+					}
+
+					var conds *nkcli.DelegationConds
 
-					fmt.Printf("Delegate to %v with these conditions:\n\n", delegatee)
-					fmt.Printf("  Kind: %v\n  Since: %v\n  Until: %v\n\n", kind, formatTime(&since), formatTime(&until))
+					switch v := req.Params[1].(type) {
+					case string:
+						conds, err = nkcli.ParseConditions(v)
+					case map[string]any:
+						conds, err = nkcli.ParseConditionsMap(v)
+					default:
+						err = errInvalidConditions
+					}
+
+					if err != nil {
+						req.Response(err)
+						continue
+					}
+
+					fmt.Printf("Delegate to %v with these conditions:\n\n%v\n\n", delegatee, conds)
+
+					if err = conds.Check(maxLifetime, time.Now()); err != nil {
+						fmt.Printf("Rejected: %v\n", err)
+						req.Response(err)
+						continue
+					}
 
 					if err = req.CheckAllow("delegate"); err != nil {
 						req.Response(err)
 						continue
 					}
 
-					d, err := nip26.CreateToken(req.Conn.KeyInfo.Privkey, delegatee, []int{kind}, &since, &until)
+					d, err := nkcli.CreateDelegation(req.Conn.KeyInfo, delegatee, conds, req.Conn.Metadata.Name)
 
 					if err != nil {
 						req.Response(err)
 						continue
 					}
 
-					sig := d.Tag()[3]
+					if err = db.SaveDelegation(d); err != nil {
+						fmt.Printf("Save delegation error: %v\n", err)
+					}
 
 					req.Response(map[string]string{
-						"from": req.Conn.PubKey,
-						"to":   delegatee,
-						"cond": d.Conditions(),
-						"sig":  sig,
+						"from": d.Delegator,
+						"to":   d.Delegatee,
+						"cond": d.Conditions,
+						"sig":  d.Sig,
 					})
 				}
 			}
@@ -209,6 +265,53 @@ Run 'nkcli help' get more.
 	return nil
 }
 
+type minedRequest struct {
+	req *nkcli.ConnectRequest
+	ev  *nostr.Event
+}
+
+// mineRequest mines the proof of work the connection asks for on ev, then
+// hands it back to the request loop to be approved.
+func mineRequest(ctx context.Context, req *nkcli.ConnectRequest, ev *nostr.Event, ch chan<- *minedRequest) {
+	mctx, cancel := context.WithTimeout(ctx, nkcli.DefaultPoWTimeout)
+	defer cancel()
+
+	if err := nkcli.MinePoW(mctx, ev, req.Conn.PoW, runtime.NumCPU()); err != nil {
+		fmt.Printf("\n  ⛏  Request ID: %v proof of work failed: %v\n", req.ID, err)
+		req.Response(errors.Join(errPoWTimeout, err))
+		return
+	}
+
+	select {
+	case ch <- &minedRequest{req, ev}:
+	case <-ctx.Done():
+	}
+}
+
+// approveSign shows ev and signs it, as it was shown, once allowed.
+func approveSign(c *cli.Context, db *nkcli.DB, req *nkcli.ConnectRequest, ev *nostr.Event) {
+	fmt.Printf("Event detail:\n\n")
+	fmt.Print(nkcli.DescribeEvent(db, ev))
+
+	if err := req.CheckAllow("sign_event"); err != nil {
+		req.Response(err)
+		return
+	}
+
+	signRequest(c, db, req, ev)
+}
+
+func signRequest(c *cli.Context, db *nkcli.DB, req *nkcli.ConnectRequest, ev *nostr.Event) {
+	if err := ev.Sign(req.Conn.KeyInfo.Privkey); err != nil {
+		req.Response(err)
+		return
+	}
+
+	archiveEvent(c, db, ev, req.Conn.Metadata.Name)
+
+	req.Response(ev)
+}
+
 func parseTags(i []interface{}) (tags nostr.Tags) {
 	for _, item := range i {
 		tags = append(tags, nostr.Tag(item.([]string)))
@@ -216,11 +319,6 @@ func parseTags(i []interface{}) (tags nostr.Tags) {
 	return
 }
 
-func printEvent(ev *nostr.Event) {
-	str, _ := json.MarshalIndent(ev, "", "  ")
-	fmt.Printf("%s\n", str)
-}
-
 func formatTime(t *time.Time) string {
 	return fmt.Sprintf("%v (%v)", t.Unix(), t.Format(time.DateTime))
 }
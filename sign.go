@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/urfave/cli/v2"
+)
+
+const compactPrefix = "nkcli:"
+
+var (
+	errInvalidCompact = errors.New("Invalid compact event")
+	errPubkeyMismatch = errors.New("Event pubkey doesn't match the chosen key")
+	errInvalidID      = errors.New("Event ID doesn't match its content")
+	errInvalidSig     = errors.New("Event signature is invalid")
+	errNoEvents       = errors.New("No events to verify")
+	errSignRejected   = errors.New("Signing rejected")
+	errInvalidEvents  = errors.New("events are invalid")
+)
+
+func signAction(c *cli.Context) error {
+	input, err := readInput(c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	event, err := decodeUnsigned(input)
+
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("encode") {
+		s, err := encodeCompactUnsigned(event)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(stdout, s)
+
+		return nil
+	}
+
+	// Prompts go to stderr so the signed event can be piped from stdout.
+	human := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = human }()
+
+	db, err := nkcli.Open(c.String("db"))
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	keyArg := c.String("key")
+
+	if len(keyArg) == 0 {
+		keyArg = event.PubKey
+	}
+
+	key, err := chooseKey(db, keyArg)
+
+	if err != nil {
+		return err
+	}
+
+	if len(event.PubKey) > 0 && event.PubKey != key.Pubkey {
+		return errPubkeyMismatch
+	}
+
+	event.PubKey = key.Pubkey
+
+	if err = minePoW(c, event); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nEvent detail:\n\n%v\n", nkcli.DescribeEvent(db, event))
+	if ok, err := prompter.Confirm("Sign this event? [y/n]"); err != nil {
+		return err
+	} else if !ok {
+		return errSignRejected
+	}
+
+	info, err := unlockKey(db, key.Pubkey)
+
+	if err != nil {
+		return err
+	}
+
+	if err = event.Sign(info.Privkey); err != nil {
+		return err
+	}
+
+	archiveEvent(c, db, event, "nkcli sign")
+
+	return writeEvent(c, event)
+}
+
+func verifyAction(c *cli.Context) error {
+	input, err := readInput(c.Args().First())
+
+	if err != nil {
+		return err
+	}
+
+	records := make([]*nkcli.VerifyRecord, 0)
+	invalid := 0
+
+	for _, item := range splitSigned(input) {
+		rec := new(nkcli.VerifyRecord)
+		records = append(records, rec)
+
+		event, err := decodeSigned(item)
+
+		if err == nil {
+			rec.ID, rec.Kind, rec.Pubkey = event.ID, event.Kind, event.PubKey
+			err = verifyEvent(event)
+		}
+
+		if err != nil {
+			invalid++
+			rec.Error = err.Error()
+			continue
+		}
+
+		rec.Valid = true
+	}
+
+	if len(records) == 0 {
+		return errNoEvents
+	}
+
+	err = render(records, func() {
+		for _, rec := range records {
+			if rec.Valid {
+				fmt.Printf("✅ %v kind %v by %v\n", rec.ID, rec.Kind, nkcli.DescribePubkey(nil, rec.Pubkey))
+			} else {
+				fmt.Printf("❌ %v\n", rec.Error)
+			}
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%v of %v %w", invalid, len(records), errInvalidEvents)
+	}
+
+	return nil
+}
+
+func verifyEvent(e *nostr.Event) error {
+	if e.GetID() != e.ID {
+		return errors.Join(errInvalidID, errors.New(e.ID))
+	}
+
+	ok, err := e.CheckSignature()
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errors.Join(errInvalidSig, errors.New(e.ID))
+	}
+
+	return nil
+}
+
+func readInput(arg string) ([]byte, error) {
+	switch {
+	case len(arg) == 0 || arg == "-":
+		return io.ReadAll(os.Stdin)
+	case strings.HasPrefix(arg, compactPrefix):
+		return []byte(arg), nil
+	default:
+		return os.ReadFile(arg)
+	}
+}
+
+func decodeCompact(input []byte) ([]byte, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(string(input)), compactPrefix)
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return nil, errors.Join(errInvalidCompact, err)
+	}
+
+	return buf, nil
+}
+
+func decodeUnsigned(input []byte) (*nostr.Event, error) {
+	input = bytes.TrimSpace(input)
+
+	if bytes.HasPrefix(input, []byte(compactPrefix)) {
+		buf, err := decodeCompact(input)
+
+		if err != nil {
+			return nil, err
+		}
+
+		input = buf
+	}
+
+	if len(input) > 0 && input[0] == '[' {
+		var arr []json.RawMessage
+
+		if err := json.Unmarshal(input, &arr); err != nil || len(arr) != 4 {
+			return nil, errInvalidCompact
+		}
+
+		input = []byte(fmt.Sprintf(`{"kind":%s,"created_at":%s,"tags":%s,"content":%s}`, arr[0], arr[1], arr[2], arr[3]))
+	}
+
+	return readUnsignedEvent(bytes.NewReader(input))
+}
+
+// splitSigned splits input into events: JSON objects one after another,
+// pretty printed or not, top-level arrays of them and compact events. What
+// can't be decoded up to the end of its line is kept to be reported.
+func splitSigned(input []byte) [][]byte {
+	items := make([][]byte, 0)
+
+	for {
+		input = bytes.TrimLeft(input, " \t\r\n")
+
+		if len(input) == 0 {
+			return items
+		}
+
+		end := bytes.IndexByte(input, '\n')
+
+		if end < 0 {
+			end = len(input)
+		}
+
+		if bytes.HasPrefix(input, []byte(compactPrefix)) {
+			items = append(items, bytes.TrimSpace(input[:end]))
+			input = input[end:]
+			continue
+		}
+
+		var v json.RawMessage
+		dec := json.NewDecoder(bytes.NewReader(input))
+
+		if err := dec.Decode(&v); err != nil {
+			items = append(items, bytes.TrimSpace(input[:end]))
+			input = input[end:]
+			continue
+		}
+
+		input = input[dec.InputOffset():]
+
+		var list []json.RawMessage
+
+		if json.Unmarshal(v, &list) == nil {
+			for _, it := range list {
+				items = append(items, it)
+			}
+		} else {
+			items = append(items, v)
+		}
+	}
+}
+
+func decodeSigned(input []byte) (*nostr.Event, error) {
+	if bytes.HasPrefix(input, []byte(compactPrefix)) {
+		buf, err := decodeCompact(input)
+
+		if err != nil {
+			return nil, err
+		}
+
+		input = buf
+	}
+
+	event := new(nostr.Event)
+
+	if err := json.Unmarshal(input, event); err != nil {
+		return nil, errors.Join(errInvalidEventArg, err)
+	}
+
+	return event, nil
+}
+
+func writeEvent(c *cli.Context, event *nostr.Event) error {
+	buf, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("compact") {
+		buf = []byte(compactPrefix + base64.RawURLEncoding.EncodeToString(buf))
+	}
+
+	buf = append(buf, '\n')
+
+	if p := c.String("out"); len(p) > 0 && p != "-" {
+		return os.WriteFile(p, buf, 0644)
+	}
+
+	if c.Bool("compact") {
+		_, err = stdout.Write(buf)
+		return err
+	}
+
+	return render(event, func() { stdout.Write(buf) })
+}
+
+func encodeCompactUnsigned(e *nostr.Event) (string, error) {
+	buf, err := json.Marshal([]any{e.Kind, e.CreatedAt.Unix(), e.Tags, e.Content})
+
+	if err != nil {
+		return "", err
+	}
+
+	return compactPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
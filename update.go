@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 	"sync"
 
 	nkcli "github.com/mdzz-club/nkcli/internal"
@@ -17,15 +16,11 @@ var (
 
 func updateAction(c *cli.Context) error {
 	dbpath := c.String("db")
-	relays := c.StringSlice("relay")
-
-	if relays == nil {
-		relays = DefaultRelays
-	}
+	relays := bootRelays(c)
 
 	for _, url := range relays {
-		if !strings.HasPrefix(url, "ws://") && !strings.HasPrefix(url, "wss://") {
-			return errors.Join(errInvalidRelayUrl, errors.New(url))
+		if err := checkRelayUrl(url); err != nil {
+			return err
 		}
 	}
 
@@ -52,15 +47,30 @@ func updateAction(c *cli.Context) error {
 	fmt.Printf("\nFound %v keys\n\n", len(list))
 
 	wg := new(sync.WaitGroup)
+	router := newRouter(c, db)
 
 	ctx := context.WithValue(c.Context, "db", db)
 
 	for _, item := range list {
 		wg.Add(1)
-		go nkcli.UpdateRelays(ctx, item, relays, wg)
+		go nkcli.UpdateRelays(ctx, item, router, wg)
 	}
 
 	wg.Wait()
 
-	return nil
+	if list, err = db.List(); err != nil {
+		return err
+	}
+
+	checked := db.VerifyKeys(ctx, nip05Verifier, list, true)
+
+	return render(nkcli.KeyRecords(list), func() {
+		for _, k := range checked {
+			if k.Nip05.Verified {
+				fmt.Printf("NIP-05 %v verified\n", k.Nip05.Identifier)
+			} else {
+				fmt.Printf("NIP-05 %v failed: %v\n", k.Nip05.Identifier, k.Nip05.Error)
+			}
+		}
+	})
 }
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	nip05Verifier nkcli.Nip05Verifier = nkcli.NewHTTPVerifier()
+)
+
+var (
+	errInvalidKeyNo   = errors.New("Invalid key No.")
+	errUnknownKey     = errors.New("Key is not in the database")
+	errNoKeys         = errors.New("You don't have any keys, generate one or import.")
+	errInvalidTimeArg = errors.New("Invalid time, use unix timestamp, YYYY-MM-DD, RFC3339 or +/-duration")
+
+	errPassphraseEnvUnset = errors.New("Passphrase environment variable is not set")
+	errPassphraseSources  = errors.New("Use only one of --passphrase-file and --passphrase-env")
+
+	errMnemonicPassphraseMismatch = errors.New("Mnemonic passphrases don't match")
+)
+
+func chooseKey(db *nkcli.DB, key string) (*nkcli.KeyInfo, error) {
+	keys, err := db.List()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) > 0 {
+		list := nkcli.SerializeKeys([]string{key})
+
+		for _, k := range keys {
+			if len(list) > 0 && k.Pubkey == list[0] {
+				return k, nil
+			}
+		}
+
+		return nil, errors.Join(errUnknownKey, errors.New(key))
+	}
+
+	if len(keys) == 0 {
+		return nil, errNoKeys
+	}
+
+	fmt.Printf("You have %v keys:\n\n", len(keys))
+
+	nkcli.PrintKeyList(keys)
+
+	n, err := prompter.Choose("  🔑 Choose your key: ", len(keys))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		return nil, errInvalidKeyNo
+	}
+
+	return keys[n], nil
+}
+
+func bootRelays(c *cli.Context) []string {
+	if c.IsSet("relay") {
+		return c.StringSlice("relay")
+	}
+
+	if len(config.Relays) > 0 {
+		return config.Relays
+	}
+
+	return DefaultRelays
+}
+
+func archiveEnabled(c *cli.Context) bool {
+	if c.IsSet("archive") {
+		return c.Bool("archive")
+	}
+
+	return config.Archive
+}
+
+func archiveEvent(c *cli.Context, db *nkcli.DB, ev *nostr.Event, source string) {
+	if !archiveEnabled(c) {
+		return
+	}
+
+	if err := db.ArchiveEvent(ev, source); err != nil {
+		fmt.Printf("Archive event error: %v\n", err)
+	}
+}
+
+func newRouter(c *cli.Context, db *nkcli.DB) *nkcli.Router {
+	router := nkcli.NewRouter(db, bootRelays(c))
+	router.Overrides = config.KeyRelays
+
+	if t := config.Timeouts.Query; t > 0 {
+		router.Timeout = time.Duration(t)
+	}
+
+	router.ConnectTimeout = time.Duration(config.Timeouts.Connect)
+	router.PublishTimeout = time.Duration(config.Timeouts.Publish)
+
+	return router
+}
+
+func setupPrompter(c *cli.Context) error {
+	prompter.Yes = c.Bool("yes")
+	prompter.Index = c.Int("index")
+
+	helper := config.CredentialHelper
+
+	if c.IsSet("credential-helper") || len(helper) == 0 {
+		helper = c.String("credential-helper")
+	}
+
+	if len(helper) > 0 {
+		prompter.Helper = &nkcli.CredentialHelper{Command: helper}
+	}
+
+	if c.IsSet("passphrase-file") && c.IsSet("passphrase-env") {
+		return errPassphraseSources
+	}
+
+	if p := c.String("passphrase-file"); len(p) > 0 {
+		buf, err := os.ReadFile(p)
+
+		if err != nil {
+			return err
+		}
+
+		prompter.Passphrase = bytes.TrimRight(buf, "\r\n")
+	}
+
+	if name := c.String("passphrase-env"); len(name) > 0 {
+		v, ok := os.LookupEnv(name)
+
+		if !ok {
+			return errors.Join(errPassphraseEnvUnset, errors.New(name))
+		}
+
+		prompter.Passphrase = []byte(v)
+	}
+
+	return nil
+}
+
+func unlockKey(db *nkcli.DB, pub string) (*nkcli.KeyInfo, error) {
+	return prompter.Unlock(db, pub, "Enter your passphrase to unlock your private key:")
+}
+
+// mnemonicPassphrase returns the BIP-39 passphrase if --mnemonic-passphrase
+// is set, from $NKCLI_MNEMONIC_PASSPHRASE or asked, twice when confirm.
+func mnemonicPassphrase(c *cli.Context, confirm bool) (string, error) {
+	if !c.Bool("mnemonic-passphrase") {
+		return "", nil
+	}
+
+	if v, ok := os.LookupEnv("NKCLI_MNEMONIC_PASSPHRASE"); ok {
+		return v, nil
+	}
+
+	pass, err := prompter.ReadSecret("Enter your mnemonic passphrase:")
+
+	if err != nil || !confirm {
+		return string(pass), err
+	}
+
+	again, err := prompter.ReadSecret("Enter it again:")
+
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.Equal(pass, again) {
+		return "", errMnemonicPassphraseMismatch
+	}
+
+	return string(pass), nil
+}
+
+func parseTimeArg(s string, now time.Time) (*time.Time, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	if s[0] == '+' || s[0] == '-' {
+		d, err := time.ParseDuration(s)
+
+		if err != nil {
+			return nil, errors.Join(errInvalidTimeArg, err)
+		}
+
+		t := now.Add(d)
+		return &t, nil
+	}
+
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		t := time.Unix(ts, 0)
+		return &t, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, errInvalidTimeArg
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	nkcli "github.com/mdzz-club/nkcli/internal"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errVanityCancelled  = errors.New("Vanity search cancelled")
+	errVanityTimeout    = errors.New("Vanity search timed out")
+	errVanityPrefixes   = errors.New("Use only one of --vanity-prefix and --hex-prefix")
+	errVanityNoMnemonic = errors.New("Vanity keys are random and have no mnemonic")
+)
+
+// vanityKey searches a random key whose npub or hex pubkey starts with the
+// prefix, until found, interrupted or --timeout.
+func vanityKey(c *cli.Context) (string, error) {
+	if c.IsSet("vanity-prefix") && c.IsSet("hex-prefix") {
+		return "", usageError{errVanityPrefixes}
+	}
+
+	if c.Bool("store-mnemonic") {
+		return "", usageError{errVanityNoMnemonic}
+	}
+
+	prefix, isHex := c.String("vanity-prefix"), c.IsSet("hex-prefix")
+
+	if isHex {
+		prefix = c.String("hex-prefix")
+	}
+
+	pattern, err := nkcli.NewVanityPattern(prefix, isHex)
+
+	if err != nil {
+		return "", usageError{err}
+	}
+
+	display := "npub1" + pattern.Prefix
+
+	if isHex {
+		display = pattern.Prefix
+	}
+
+	workers := c.Int("threads")
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	fmt.Printf("Searching a key starting with %v on %v threads, 1 in %.0f keys matches.\nPress Ctrl-C to cancel.\n\n", display, workers, pattern.Difficulty())
+
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+	defer stop()
+
+	if t := c.Duration("timeout"); t > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t)
+		defer cancel()
+	}
+
+	priv, err := nkcli.SearchVanity(ctx, pattern, workers, func(tried uint64, elapsed time.Duration) {
+		rate := float64(tried) / elapsed.Seconds()
+
+		fmt.Printf("\r  %v tried, %.0f keys/s, %.1f%% chance so far, %v expected   ", tried, rate, pattern.Chance(tried)*100, formatETA(pattern.Difficulty()/rate))
+	})
+
+	fmt.Println()
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "", errVanityTimeout
+	case errors.Is(err, context.Canceled):
+		return "", errVanityCancelled
+	}
+
+	return priv, err
+}
+
+// formatETA formats seconds, durations beyond a year are given in years.
+func formatETA(seconds float64) string {
+	const year = 365.25 * 24 * 3600
+
+	if seconds >= year {
+		return fmt.Sprintf("%.3g years", seconds/year)
+	}
+
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}